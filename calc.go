@@ -72,6 +72,9 @@ const K = 273.15
 // L is the temperatue lapse rate in the troposphere in K/m.
 const L = 0.0065
 
+// Mv is the molar mass of water vapor in kg/mol.
+const Mv = 0.018016
+
 // Components is the amount of power in watts each component of the model requires.
 type Components struct {
 	AT float64
@@ -93,11 +96,11 @@ var PowerTOT = Ptot
 
 // Psimp calculates a simplified version of the total power required, equal to
 // the net total power of Pat, Prr, Pwb, Ppe, divided by the drive chain efficiency ec,
-// but without contributions from Pke.
+// but without contributions from Pke. The CdA is fixed regardless of the yaw
+// angle; use Model.Power with a Rider.Curve to account for yaw.
 func Psimp(rho, cda, crr, va, vg, gr, mt, g, ec, fw float64) float64 {
-	// NOTE: (tf - ti) must not equal 0 so we use tf = 1
-	comp := Pcomp(rho, cda, crr, va, vg, gr, mt, 0, 0, 0, 0, 1, g, ec, fw, 0)
-	return comp.AT + comp.RR + comp.WB + comp.PE // comp.KE = 0
+	comp := positional(rho, cda, crr, 0, 0, mt, 0, g, ec, fw, 0).steady(cda, va, vg, gr)
+	return comp.AT + comp.RR + comp.WB + comp.PE
 }
 
 // Power is an alias for the Psimp function.
@@ -105,30 +108,16 @@ var Power = Psimp
 
 // Vg calculates the velocity of the bicycle relative to the ground in m/s based
 // on the net total power p given rho, cda, crr, vw, dw, db, gr, mt, g, ec and fw.
-// NOTE: this method is only valid for velocities between 0 and 100 m/s.
+// Like the other positional functions, cda does not vary with the yaw angle (see
+// Model.Speed for a CdACurve). NOTE: NaN is returned if no such velocity
+// exists, see Model.Speed.
 func Vg(p, rho, cda, crr, vw, dw, db, gr, mt, g, ec, fw float64) float64 {
-	// epsilon is some small value that determines when we will stop the search
-	const epsilon = 1e-6
-	// max is the maxmium number of iterations of the search
-	const max = 100
-
-	vgl, vgm, vgh := 0.0, 50.0, 100.0
-	for j := 0; j < max; j++ {
-		pm := Psimp(rho, cda, crr, Va(vgm, vw, dw, db), vgm, gr, mt, g, ec, fw)
-		if Eqf(pm, p, epsilon) {
-			break
-		}
-
-		if pm > p {
-			vgh = vgm
-		} else {
-			vgl = vgm
-		}
-
-		vgm = (vgh + vgl) / 2.0
+	m := positional(rho, cda, crr, vw, dw, mt, 0, g, ec, fw, 0)
+	vg, err := m.Speed(p, Conditions{Grade: gr, Heading: db})
+	if err != nil {
+		return math.NaN()
 	}
-
-	return vgm
+	return vg
 }
 
 // GroundVelocity is an alias for the Vg function.
@@ -139,7 +128,7 @@ var Velocity = Vg
 
 // T calculates the duration in seconds of a performance over distance d in metres
 // with net total power p given rho, cda, crr, vw, dw, db, gr, mt, g, ec and fw.
-// NOTE: this method is only valid for velocities between 0 and 100 m/s.
+// NOTE: NaN is returned if no velocity can be maintained, see Model.Speed.
 func T(p, d, rho, cda, crr, vw, dw, db, gr, mt, g, ec, fw float64) float64 {
 	return d / Vg(p, rho, cda, crr, vw, dw, db, gr, mt, g, ec, fw)
 }
@@ -149,7 +138,7 @@ var Time = T
 
 // D calculates the distance in metres of a performance over duration t in seconds
 // with net total power p given rho, cda, crr, vw, dw, db, gr, mt, g, ec and fw.
-// NOTE: this method is only valid for velocities between 0 and 100 m/s.
+// NOTE: NaN is returned if no velocity can be maintained, see Model.Speed.
 func D(p, t, rho, cda, crr, vw, dw, db, gr, mt, g, ec, fw float64) float64 {
 	return t * Vg(p, rho, cda, crr, vw, dw, db, gr, mt, g, ec, fw)
 }
@@ -160,13 +149,8 @@ var Distance = D
 // Pcomp calculates the total power required, broken down by the components of
 // Pat, Prr, Pwb, Ppe, and Pke, each divided by the drive chain efficiency ec.
 func Pcomp(rho, cda, crr, va, vg, gr, mt, r, vgi, vgf, ti, tf, g, ec, fw, i float64) Components {
-	return Components{
-		AT: Pat(rho, cda, fw, va, vg) / ec,
-		RR: Prr(vg, gr, crr, mt, g) / ec,
-		WB: Pwb(vg) / ec,
-		PE: Ppe(vg, mt, g, gr) / ec,
-		KE: Pke(mt, i, r, vgi, vgf, ti, tf) / ec,
-	}
+	m := positional(rho, cda, crr, 0, 0, mt, r, g, ec, fw, i)
+	return m.pcomp(va, vg, gr, vgi, vgf, ti, tf)
 }
 
 // PowerCOMP is an alias for the Pcomp function
@@ -293,6 +277,33 @@ func Rho(h, g float64) float64 {
 // AirDensity is an alias for the Rho function.
 var AirDensity = Rho
 
+// MoistRho calculates the density of moist air given the barometric pressure p
+// in Pa, the temperature t in Celsius and the relative humidity rh (0-1) by
+// treating the air as a mixture of dry air and water vapor, each of which obeys
+// the ideal gas law.
+func MoistRho(p, t, rh float64) float64 {
+	pv := rh * Psat(t)
+	return ((p-pv)*M + pv*Mv) / (R * (t + K))
+}
+
+// MoistAirDensity is an alias for the MoistRho function.
+var MoistAirDensity = MoistRho
+
+// Psat calculates the saturation vapor pressure of water in Pa at temperature t
+// in Celsius using the Buck equation.
+func Psat(t float64) float64 {
+	return 611.21 * math.Exp((18.678-t/234.5)*(t/(257.14+t)))
+}
+
+// SaturationVaporPressure is an alias for the Psat function.
+var SaturationVaporPressure = Psat
+
+// RelativeHumidity calculates the relative humidity (0-1) of air at temperature t
+// with a dew point of td, both in Celsius.
+func RelativeHumidity(t, td float64) float64 {
+	return Psat(td) / Psat(t)
+}
+
 // AltitudeAdjust calculates the equivalent sustainable power at altitude h metres
 // compared to a sea level power of p based on the formula derived from Townsend et al
 // "Prediction of Critical Power and W′ in Hypoxia: Application to Work-Balance Modelling".
@@ -259,3 +259,59 @@ func TestAltitudeAdjust(t *testing.T) {
 		}
 	}
 }
+
+func BenchmarkVelocity(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		Velocity(333.175, 1.1921, TopsCdA, 0.008, 2.7778, 180, 45, 0.079, 85.0, G, 0.95, Fw)
+	}
+}
+
+func TestMoistAirDensity(t *testing.T) {
+	tests := []struct {
+		p, t, rh, expected float64
+	}{
+		{P0, 15, 0, Rho0},
+		{P0, 35, 0.6, 1.1311},
+		{90000, 25, 0.5, 1.0446},
+	}
+	for _, tt := range tests {
+		actual := MoistAirDensity(tt.p, tt.t, tt.rh)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("MoistAirDensity(%.3f, %.3f, %.3f): got: %.3f, want: %.3f",
+				tt.p, tt.t, tt.rh, actual, tt.expected)
+		}
+	}
+}
+
+func TestSaturationVaporPressure(t *testing.T) {
+	tests := []struct {
+		t, expected float64
+	}{
+		{0, 611.21},
+		{20, 2338.8},
+		{35, 5626.8},
+	}
+	for _, tt := range tests {
+		actual := SaturationVaporPressure(tt.t)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("SaturationVaporPressure(%.3f): got: %.3f, want: %.3f",
+				tt.t, actual, tt.expected)
+		}
+	}
+}
+
+func TestRelativeHumidity(t *testing.T) {
+	tests := []struct {
+		t, td, expected float64
+	}{
+		{20, 20, 1},
+		{30, 20, 0.5508},
+	}
+	for _, tt := range tests {
+		actual := RelativeHumidity(tt.t, tt.td)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("RelativeHumidity(%.3f, %.3f): got: %.3f, want: %.3f",
+				tt.t, tt.td, actual, tt.expected)
+		}
+	}
+}
@@ -1,159 +1,251 @@
 // calc provides a CLI for calculating either the power required or the time
-// achievable for a given performance.
+// achievable for a given performance. Without a subcommand, the flags determine
+// what is calculated; see 'calc help' for the subcommands.
 package main
 
 import (
 	"flag"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/scheibo/calc"
 )
 
-// RADII maps from tire width to radius
-var RADII = map[int64]float64{
-	20: calc.R700x20,
-	22: calc.R700x22,
-	23: calc.R700x23,
-	25: calc.R700x25,
-	28: calc.R700x28,
-}
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := COMMANDS[os.Args[1]]; ok {
+			cmd.run(os.Args[2:])
+			return
+		}
+		if os.Args[1] == "help" {
+			help()
+			return
+		}
+	}
 
-// COMPASS maps from cardinal direction to degrees
-var COMPASS = map[string]float64{
-	"N":   0,
-	"NNE": 22.5,
-	"NE":  45,
-	"ENE": 67.5,
-	"E":   90,
-	"ESE": 122.5,
-	"SE":  135,
-	"SSE": 157.5,
-	"S":   180,
-	"SSW": 202.5,
-	"SW":  225,
-	"WSW": 247.5,
-	"W":   270,
-	"WNW": 292.5,
-	"NW":  315,
-	"NNW": 337.5,
-}
+	var mf modelFlags
+	var cf courseFlags
+	var temp, pressure, humidity, t, p, cp, wp, ftp float64
+	pF := DistributionFlag{Value: -1}
+	var samples int
+	var runs, efforts, cpmodel, solve, format, output string
+	var sweeps SweepFlag
+	var pace, sensitivity bool
+	var dur time.Duration
 
-type DirectionFlag struct {
-	Direction float64
-}
+	mf.register(flag.CommandLine, true)
+	cf.register(flag.CommandLine)
+	mf.uncertain = true
 
-func (df *DirectionFlag) String() string {
-	return strconv.FormatFloat(df.Direction, 'f', -1, 64)
-}
+	flag.Float64Var(&temp, "temp", 15, "air temperature in Celsius")
+	flag.Float64Var(&pressure, "pressure", calc.P0/100.0, "barometric pressure in hPa")
+	flag.Float64Var(&humidity, "humidity", 0, "relative humidity in % (0 to 100)")
 
-func (df *DirectionFlag) Set(v string) error {
-	d, ok := COMPASS[strings.ToUpper(v)]
-	if ok {
-		df.Direction = d
-		return nil
-	}
+	flag.Var(&pF, "p", "power in watts")
+	flag.IntVar(&samples, "samples", 10000, "number of samples when cda, crr, mr, vw or p are uncertain ('x±sd', 'min~max' or 'min~mode~max')")
+	flag.DurationVar(&dur, "t", -1, "duration in minutes and seconds ('12m34s')")
 
-	d, err := strconv.ParseFloat(v, 64)
-	if err == nil {
-		df.Direction = d
-		return nil
-	}
+	flag.Float64Var(&ftp, "ftp", 0, "functional threshold power in watts, to estimate the training load")
+	flag.Float64Var(&cp, "cp", 0, "critical power in watts, to check the performance is sustainable")
+	flag.Float64Var(&wp, "wprime", 20, "W′ in kJ")
 
-	return fmt.Errorf("invalid direction '%s'", v)
-}
+	flag.BoolVar(&sensitivity, "sensitivity", false, "report the time saved by changing each variable")
+	flag.BoolVar(&pace, "pace", false, "optimize the power of each segment of the route given cp and wprime")
+	flag.StringVar(&efforts, "efforts", "", "FIT or CSV file of power to estimate cp and wprime from")
+	flag.StringVar(&cpmodel, "cpmodel", "2p", "critical power model to fit efforts with (2p, 3p or morton)")
 
-func main() {
-	var rho, cda, crr, vw, e, gr, h, mt, mr, mb, r, t, d, p float64
-	var dw, db DirectionFlag
-	var tire int64
-	var err error
-	var dur time.Duration
+	flag.StringVar(&solve, "solve", "", "variable to solve for given both p and t (cda, crr, mt, gr, vw, rho or ec)")
 
-	flag.Float64Var(&rho, "rho", calc.Rho0, "air density in kg/m*3")
-	flag.Float64Var(&cda, "cda", 0.325, "coefficient of drag area")
-	flag.Float64Var(&crr, "crr", calc.Crr, "coefficient of rolling resistance")
+	flag.Var(&sweeps, "sweep", "input to vary ('p=200:400:20' or 'gr=0,5,10'), may be repeated")
+	flag.StringVar(&output, "output", "", "output of a sweep (speed, time or power), or matched by solve (time or power)")
+	flag.StringVar(&format, "format", "text", "output format (text, json or csv, or markdown for a sweep)")
 
-	flag.Float64Var(&mr, "mr", 67.0, "total mass of the rider in kg")
-	flag.Float64Var(&mb, "mb", 8.0, "total mass of the bicycle in kg")
+	flag.StringVar(&runs, "runs", "", "CSV file of constant speed runs (p, vg, va, gr) to estimate cda and crr from")
 
-	flag.Int64Var(&tire, "tire", 23, "the tire width in mm")
-
-	flag.Float64Var(&vw, "vw", 0, "the wind speed in m/s")
-	flag.Var(&dw, "dw", "the cardinal direction the wind originates from")
-	flag.Var(&db, "db", "the cardinal direction the bicycle is travelling")
+	flag.Usage = func() {
+		help()
+		fmt.Fprintf(os.Stderr, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-	flag.Float64Var(&e, "e", 0, "total elevation gained in m")
-	flag.Float64Var(&gr, "gr", 0, "average grade")
-	flag.Float64Var(&h, "h", 0, "median elevation")
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if _, err := profiles(flag.CommandLine, set); err != nil {
+		exit(err)
+	}
 
-	flag.Float64Var(&d, "d", -1, "distance travelled in m")
-	flag.Float64Var(&p, "p", -1, "power in watts")
-	flag.DurationVar(&dur, "t", -1, "duration in minutes and seconds ('12m34s')")
+	p = pF.Value
+	u := mf.uncertainty(pF, samples)
+	uncertain := u.Power != nil || len(u.Variables) > 0
+	if uncertain && (p == -1 || dur != -1 || solve != "" || pace || sensitivity || len(sweeps) > 0) {
+		exit(fmt.Errorf("uncertain values can only be provided when calculating the time for p"))
+	}
 
-	flag.Parse()
+	structured := format == "json" || format == "csv"
+	if !structured && format != "text" && (format != "markdown" || len(sweeps) == 0) {
+		exit(fmt.Errorf("invalid format '%s'", format))
+	}
+	if structured && len(sweeps) == 0 && (uncertain || solve != "" || sensitivity || runs != "") {
+		exit(fmt.Errorf("format %s can't be provided with uncertain values, solve, sensitivity or runs", format))
+	}
 
-	verify("rho", rho)
-	verify("cda", cda)
-	verify("crr", crr)
+	verify("ftp", ftp)
+	verify("cp", cp)
+	verify("wprime", wp)
 
-	verify("mr", mr)
-	verify("mb", mb)
-	mt = mr + mb
+	weather := set["temp"] || set["pressure"] || set["humidity"]
+	if weather {
+		if set["rho"] {
+			exit(fmt.Errorf("rho can't be provided with temp, pressure or humidity"))
+		}
+		verify("pressure", pressure)
+		verify("humidity", humidity)
+		if humidity > 100 {
+			exit(fmt.Errorf("humidity must be at most 100%% but was %f", humidity))
+		}
+	}
 
-	r, err = tireRadius(tire)
+	m, err := mf.model(set)
 	if err != nil {
 		exit(err)
 	}
+	mr := mf.mr.Value
 
-	verify("vw", vw)
-	verify("h", h)
-	if h != 0 {
-		r := calc.Rho(h, calc.G)
-		// if both are specified, make sure they agree
-		if rho != calc.Rho0 && r != rho {
-			exit(fmt.Errorf("specified both rho=%f and h=%f but they do not agree", rho, h))
-		}
-		rho = r
+	fi, _ := os.Stdout.Stat()
+	pipe := (fi.Mode() & os.ModeCharDevice) == 0
+
+	if runs != "" {
+		regress(m, runs, pipe)
+		return
 	}
 
-	// error correct in case grade was passed in as a %
-	if gr > 1 || gr < -1 {
-		gr = gr / 100
+	var fit *calc.CPFit
+	if efforts != "" {
+		if set["cp"] || set["wprime"] {
+			exit(fmt.Errorf("cp and wprime can't be provided with efforts"))
+		}
+		f, err := fitEfforts(efforts, cpmodel)
+		if err != nil {
+			exit(err)
+		}
+		if !set["d"] && cf.gpx == "" {
+			if pipe {
+				fmt.Printf("-cp=%.2f -wprime=%.2f\n", f.CP, f.WPrime/1000)
+			} else {
+				fmt.Printf("-cp=%.2f -wprime=%.2f (Pmax %.2f W, RMSE %.2f W, R² %.4f)\n",
+					f.CP, f.WPrime/1000, f.Pmax, f.RMSE, f.R2)
+			}
+			return
+		}
+		fit, cp, wp = &f, f.CP, f.WPrime/1000
 	}
 
-	if d <= 0 {
-		exit(fmt.Errorf("d must be positive but was %f", d))
+	// a sweep of speeds is independent of the distance
+	if len(sweeps) > 0 && !set["d"] && cf.gpx == "" {
+		cf.d = 1000
 	}
 
-	if e > 0 {
-		// if both are specified, make sure they agree
-		if gr > 0 && ((d*gr != e) || (e/d != gr)) {
-			exit(fmt.Errorf("specified both e=%f and gr=%f but they do not agree", e, gr))
+	course, err := cf.course(&m, &mf, set)
+	if err != nil {
+		exit(err)
+	}
+	d, gr := course.Distance(), course.Grade()
+
+	in := inputs{Yaw: mf.yaw}
+	if weather {
+		// use the elevation of the route unless it was specified
+		h := mf.h
+		if cf.gpx != "" && !set["h"] {
+			h = course.Elevation()
+		}
+		m.Environment.Rho = airDensity(h, temp, pressure, humidity, set["temp"], set["pressure"])
+		if set["temp"] {
+			in.Temp = &temp
+		}
+		if set["pressure"] {
+			in.Pressure = &pressure
+		}
+		if set["humidity"] {
+			in.Humidity = &humidity
 		}
-		gr = e / d
 	}
 
-	fi, _ := os.Stdout.Stat()
-	pipe := (fi.Mode() & os.ModeCharDevice) == 0
+	if pace && (p != -1 || dur != -1) {
+		exit(fmt.Errorf("pace can't be provided with p or t"))
+	}
+	if len(sweeps) > 0 {
+		if solve != "" || pace || sensitivity || fit != nil {
+			exit(fmt.Errorf("sweep can't be provided with solve, pace, sensitivity or efforts"))
+		}
+		if p != -1 {
+			verify("p", p)
+		}
+		if dur != -1 {
+			verify("t", float64(dur))
+			t = float64(dur / time.Second)
+		}
+		sweep(course, m, p, t, sweeps, output, format, set["d"] || cf.gpx != "")
+	} else if solve != "" {
+		v, err := calc.ParseVariable(solve)
+		if err != nil {
+			exit(err)
+		}
+		if p == -1 || dur == -1 {
+			exit(fmt.Errorf("p and t must both be provided with solve"))
+		}
+		verify("p", p)
+		verify("t", float64(dur))
+		t = float64(dur / time.Second)
+
+		out := calc.OutputTime
+		if output != "" {
+			if out = OUTPUTS[output]; out != calc.OutputTime && out != calc.OutputPower {
+				exit(fmt.Errorf("invalid output '%s'", output))
+			}
+		}
+		x, err := course.Solve(m, v, p, t, out)
+		if err != nil {
+			exit(fmt.Errorf("unable to solve for %s: %s", v, err))
+		}
+		name := v.String()
+		if v == calc.VarMass {
+			name, x = "mr", x-mf.mb
+		}
 
-	if p != -1 {
+		if pipe {
+			fmt.Printf("-%s=%.5g\n", name, x)
+		} else {
+			fmt.Printf("-%s=%.5g (%.2f km @ %.2f%% @ %.2f W = %s)\n", name, x, d/1000, gr*100, p, fmtDuration(dur))
+		}
+	} else if p != -1 {
 		verify("p", p)
 		if dur != -1 {
 			exit(fmt.Errorf("t and p can't both be provided"))
 		}
 
-		t = calc.T(p, d, rho, cda, crr, vw, dw.Direction, db.Direction, gr, mt, calc.G, calc.Ec, calc.Fw)
+		res, err := course.Time(m, p)
+		if err != nil {
+			exit(fmt.Errorf("unable to calculate time for p=%f: %s", p, err))
+		}
+		t = res.Time
 		dur = time.Duration(t) * time.Second
 		wkg := p / mr
 
-		if pipe {
+		if uncertain {
+			forecast(course, m, p, u, pipe)
+		} else if structured {
+			emit(summarize(course, m, res, ftp, in), format)
+		} else if pipe {
 			fmt.Println(dur)
 		} else {
-			fmt.Printf("%.2f km @ %.2f%% @ %.2f W (%.2f W/kg) = %s\n", d/1000, gr*100, p, wkg, fmtDuration(dur))
+			fmt.Printf("%.2f km @ %.2f%% @ %.2f W (%.2f W/kg) = %s%s\n", d/1000, gr*100, p, wkg, fmtDuration(dur), load(ftp, res))
+		}
+		check(cp, wp, res)
+		if sensitivity {
+			report(course, m, res.Power, pipe)
 		}
 	} else if dur != -1 {
 		verify("t", float64(dur))
@@ -162,53 +254,265 @@ func main() {
 			exit(fmt.Errorf("p and t can't both be provided"))
 		}
 
-		vg := d / t
-		va := calc.Va(vg, vw, dw.Direction, db.Direction)
-
-		comp := calc.Pcomp(rho, cda, crr, va, vg, gr, mt, r, vg, vg, 0, t, calc.G, calc.Ec, calc.Fw, calc.I)
-		ptot := comp.AT + comp.RR + comp.WB + comp.PE + comp.KE
+		res, err := course.Power(m, t)
+		if err != nil {
+			exit(fmt.Errorf("unable to calculate power for t=%s: %s", dur, err))
+		}
+		comp := res.Components()
+		ptot := res.Power
 		wkg := ptot / mr
 
-		if pipe {
+		if structured {
+			emit(summarize(course, m, res, ftp, in), format)
+		} else if pipe {
 			fmt.Println(ptot)
 		} else {
-			fmt.Printf("%s (%.2f km @ %.2f%%) = %.2f W (%.2f W/kg) = AT:%.2f W + RR:%.2f W + WB:%.2f W + PE:%.2f W\n",
-				fmtDuration(dur), d/1000, gr*100, ptot, wkg, comp.AT, comp.RR, comp.WB, comp.PE)
+			fmt.Printf("%s (%.2f km @ %.2f%%) = %.2f W (%.2f W/kg) = AT:%.2f W + RR:%.2f W + WB:%.2f W + PE:%.2f W%s\n",
+				fmtDuration(dur), d/1000, gr*100, ptot, wkg, comp.AT, comp.RR, comp.WB, comp.PE, load(ftp, res))
+		}
+		check(cp, wp, res)
+		if sensitivity {
+			report(course, m, res.Power, pipe)
+		}
+	} else if pace {
+		if cp == 0 {
+			exit(fmt.Errorf("cp or efforts must be specified with pace"))
+		}
+		plan, err := course.Pace(m, calc.CriticalPower{CP: cp, WPrime: wp * 1000}, calc.SkibaDifferential)
+		if err != nil {
+			exit(fmt.Errorf("unable to optimize pacing: %s", err))
+		}
+
+		if structured {
+			emit(summarize(course, m, plan.Result, ftp, in), format)
+		} else if pipe {
+			for _, s := range plan.Splits {
+				fmt.Println(s.Power)
+			}
+		} else {
+			x := 0.0
+			for _, s := range plan.Splits {
+				fmt.Printf("%.2f-%.2f km @ %.2f%% @ %.2f W = %s\n",
+					x/1000, (x+s.Length)/1000, s.Grade*100, s.Power, fmtDuration(time.Duration(s.Time*float64(time.Second))))
+				x += s.Length
+			}
+			fmt.Printf("%.2f km @ %.2f%% @ %.2f W = %s (%s faster than %.2f W)%s\n", d/1000, gr*100, plan.Power,
+				fmtDuration(time.Duration(plan.Time)*time.Second), fmtDuration(time.Duration(plan.Gain*float64(time.Second))),
+				plan.Even.Power, load(ftp, plan.Result))
+		}
+	} else if fit != nil {
+		res, err := course.Best(m, fit.Power)
+		if err != nil {
+			exit(fmt.Errorf("unable to calculate best time: %s", err))
+		}
+		p = res.Power
+		dur = time.Duration(res.Time) * time.Second
+		wkg := p / mr
+
+		if structured {
+			emit(summarize(course, m, res, ftp, in), format)
+		} else if pipe {
+			fmt.Println(dur)
+		} else {
+			fmt.Printf("%.2f km @ %.2f%% @ %.2f W (%.2f W/kg) = %s%s\n", d/1000, gr*100, p, wkg, fmtDuration(dur), load(ftp, res))
 		}
 	} else {
-		exit(fmt.Errorf("p or t must be specified"))
+		exit(fmt.Errorf("p, t or efforts must be specified"))
+	}
+}
+
+// airDensity calculates the density of moist air at altitude h given the
+// temperature t in Celsius, pressure p in hPa and relative humidity rh in %. If
+// the temperature or pressure are unknown the standard atmosphere is assumed.
+func airDensity(h, t, p, rh float64, temp, pressure bool) float64 {
+	if !temp {
+		t = calc.T0 - calc.L*h - calc.K
+	}
+	pa := p * 100
+	if !pressure {
+		pa = calc.AirPressure(h, t)
+	}
+	return calc.MoistRho(pa, t, rh/100)
+}
+
+// load describes the training load of the performance res for a rider with a
+// functional threshold power of ftp watts, if provided.
+func load(ftp float64, res calc.Result) string {
+	if ftp <= 0 {
+		return ""
+	}
+	l, err := calc.TrainingLoad(res.PowerSeries(1), 1, ftp)
+	if err != nil {
+		exit(err)
+	}
+	return fmt.Sprintf(" (NP %.2f W, IF %.2f, TSS %.0f)", l.NP, l.IF, l.TSS)
+}
+
+// forecast prints the distribution of times taken by m to ride the course at p
+// watts given the uncertainty u.
+func forecast(course calc.Course, m calc.Model, p float64, u calc.Uncertainty, pipe bool) {
+	f, err := course.Forecast(m, p, u)
+	if err != nil {
+		exit(fmt.Errorf("unable to forecast time for p=%f: %s", p, err))
+	}
+	sec := func(t float64) time.Duration {
+		return time.Duration(t * float64(time.Second))
+	}
+
+	if pipe {
+		fmt.Println(sec(f.Percentile(10)).Round(time.Second), sec(f.Percentile(50)).Round(time.Second),
+			sec(f.Percentile(90)).Round(time.Second))
+		return
+	}
+	d := course.Distance()
+	fmt.Printf("%.2f km @ %.2f%% @ %.2f W = P10 %s, P50 %s, P90 %s (mean %s ± %s)\n",
+		d/1000, course.Grade()*100, p, fmtDuration(sec(f.Percentile(10))), fmtDuration(sec(f.Percentile(50))),
+		fmtDuration(sec(f.Percentile(90))), fmtDuration(sec(f.Mean)), fmtDuration(sec(f.StdDev)))
+	if f.Failures > 0 {
+		fmt.Printf("unable to complete the course in %d of %d samples\n", f.Failures, u.Samples)
+	}
+}
+
+// UNITS are the changes of each variable reported on by -sensitivity
+var UNITS = map[calc.Variable]float64{
+	calc.VarCdA:        -0.01,
+	calc.VarCrr:        -0.001,
+	calc.VarMass:       -1,
+	calc.VarGrade:      -0.01,
+	calc.VarWind:       -1,
+	calc.VarRho:        -0.01,
+	calc.VarEfficiency: 0.01,
+}
+
+// report prints the sensitivity of the time taken by m to ride the course at p
+// watts to a change in the power and each variable.
+func report(course calc.Course, m calc.Model, p float64, pipe bool) {
+	// dp is the change in power reported on
+	const dp = 10
+
+	s, err := course.Sensitivity(m, p)
+	if err != nil {
+		exit(fmt.Errorf("unable to calculate sensitivity: %s", err))
+	}
+
+	if pipe {
+		fmt.Println("variable,value,time,power")
+		fmt.Printf("p,%f,%f,1\n", p, s.Time)
+		for _, x := range s.Partials {
+			fmt.Printf("%s,%f,%f,%f\n", x.Variable, x.Value, x.Time, x.Power)
+		}
+		return
+	}
+
+	fmt.Printf("%+10.4g p   = %+.2f s\n", float64(dp), s.Time*dp)
+	for _, x := range s.Partials {
+		u := UNITS[x.Variable]
+		name := x.Variable.String()
+		if x.Variable == calc.VarMass {
+			name = "mr"
+		}
+		fmt.Printf("%+10.4g %-3s = %+.2f s (%+.2f W)\n", u, name, x.Time*u, x.Power*u)
 	}
 }
 
-func fmtDuration(d time.Duration) string {
-	d = d.Round(time.Second)
-	h := d / time.Hour
-	d -= h * time.Hour
-	m := d / time.Minute
-	d -= m * time.Minute
-	s := d / time.Second
-	if h > 0 {
-		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
-	}
-	return fmt.Sprintf("%d:%02d", m, s)
+// check warns if the performance res would exhaust the W′ of a rider with a
+// critical power of cp watts and a W′ of wp kJ.
+func check(cp, wp float64, res calc.Result) {
+	if cp <= 0 {
+		return
+	}
+	min, err := calc.CriticalPower{CP: cp, WPrime: wp * 1000}.Check(res, calc.SkibaDifferential)
+	if err == calc.ErrExhausted {
+		fmt.Fprintf(os.Stderr, "warning: W′ exhausted (minimum W′bal %.2f kJ)\n", min/1000)
+	} else if err != nil {
+		exit(err)
+	}
 }
 
-func tireRadius(tire int64) (float64, error) {
-	r, ok := RADII[tire]
+// fitEfforts fits the critical power model to the mean maximal power curve of
+// the FIT or CSV file at path.
+func fitEfforts(path, model string) (calc.CPFit, error) {
+	models := map[string]calc.CPModel{"2p": calc.TwoParameter, "3p": calc.ThreeParameter, "morton": calc.Morton}
+	cpm, ok := models[strings.ToLower(model)]
 	if !ok {
-		return 0, fmt.Errorf("invalid tire width '%d'", tire)
+		return calc.CPFit{}, fmt.Errorf("invalid critical power model '%s'", model)
+	}
+
+	power, err := readPower(path)
+	if err != nil {
+		return calc.CPFit{}, err
+	}
+
+	var durations []float64
+	for _, d := range calc.EffortDurations {
+		// the 2-parameter model is only valid for efforts between 2 and 20 minutes
+		if cpm != calc.TwoParameter || (d >= 120 && d <= 1200) {
+			durations = append(durations, d)
+		}
+	}
+	f, err := calc.FitCriticalPower(calc.MeanMaximalPower(power, 1, durations), cpm)
+	if err != nil {
+		return calc.CPFit{}, fmt.Errorf("unable to estimate cp and wprime from '%s': %s", path, err)
+	}
+	return f, nil
+}
+
+// readPower reads the power series from the FIT or CSV file at path.
+func readPower(path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(strings.ToLower(path), ".fit") {
+		rs, err := calc.ParseFIT(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read fit file '%s': %s", path, err)
+		}
+		return calc.ResamplePower(rs), nil
+	}
+	power, err := calc.ReadPower(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read power file '%s': %s", path, err)
 	}
-	return r, nil
+	return power, nil
 }
 
-func verify(s string, x float64) {
-	if x < 0 {
-		exit(fmt.Errorf("%s must be non negative but was %f", s, x))
+func regress(m calc.Model, path string, pipe bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		exit(err)
+	}
+	defer f.Close()
+
+	runs, err := calc.ReadRuns(f)
+	if err != nil {
+		exit(fmt.Errorf("unable to read runs file '%s': %s", path, err))
+	}
+	reg, err := m.Regress(runs)
+	if err != nil {
+		exit(fmt.Errorf("unable to estimate cda and crr: %s", err))
+	}
+
+	if pipe {
+		fmt.Printf("-cda=%.4f -crr=%.5f\n", reg.CdA, reg.Crr)
+	} else {
+		fmt.Printf("-cda=%.4f -crr=%.5f (CdA ±%.4f, Crr ±%.5f @ %.0f%%, RMSE %.2f W over %d runs)\n",
+			reg.CdA, reg.Crr, reg.CdAError, reg.CrrError, calc.Confidence*100, reg.RMSE, len(runs))
 	}
 }
 
-func exit(err error) {
-	fmt.Fprintf(os.Stderr, "%s\n", err)
-	flag.PrintDefaults()
-	os.Exit(1)
+func readCourse(path string) (calc.Course, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c, err := calc.ReadGPX(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read gpx file '%s': %s", path, err)
+	}
+	return c, nil
 }
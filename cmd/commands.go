@@ -0,0 +1,587 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/scheibo/calc"
+)
+
+// command is a subcommand of the CLI.
+type command struct {
+	// summary is a short description of the command.
+	summary string
+	run     func(args []string)
+}
+
+// COMMANDS maps from the name of each subcommand to the command
+var COMMANDS map[string]command
+
+func init() {
+	// initialized here as the commands refer to COMMANDS for their usage
+	COMMANDS = map[string]command{
+		"power":    {"constant power required to ride a course in a given time", power},
+		"time":     {"time taken to ride a course at a constant power", duration},
+		"distance": {"distance ridden in a given time at a constant power", distance},
+		"speed":    {"ground velocity at a constant power", speed},
+		"density":  {"air density at an altitude and in given weather", density},
+		"cda":      {"typical CdA of a rider given their height and mass", cda},
+		"altitude": {"equivalent power at altitude to a power at sea level", altitude},
+		"mmp":      {"mean maximal power curve and FTP of a FIT or CSV file", mmp},
+		"repl":     {"interactive session for comparing changes to the inputs", repl},
+		"serve":    {"HTTP server exposing a JSON API for the model", serve},
+	}
+}
+
+// help prints the usage of the CLI and a summary of each subcommand.
+func help() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n       %s <command> [flags]\n\nCommands:\n", os.Args[0], os.Args[0])
+	var names []string
+	for name := range COMMANDS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s%s\n", name, COMMANDS[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+// subcommand returns the flag set for the named subcommand with arguments args
+// described by usage, which is used when exiting due to an error.
+func subcommand(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags]%s\n\n%s.\n\n", os.Args[0], name, args, capitalize(COMMANDS[name].summary))
+		fs.PrintDefaults()
+	}
+	usage = fs.PrintDefaults
+	return fs
+}
+
+// capitalize returns s with its first letter in upper case.
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// parse parses the args with the flag set and returns which flags were set.
+func parse(fs *flag.FlagSet, args []string) map[string]bool {
+	fs.Parse(args)
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if _, err := profiles(fs, set); err != nil {
+		exit(err)
+	}
+	return set
+}
+
+// modelFlags are the flags describing the rider, bicycle and environment which
+// are shared by subcommands.
+type modelFlags struct {
+	rho, mb, ec, h   float64
+	cda, crr, mr, vw DistributionFlag
+	tire             int64
+	dw               DirectionFlag
+	yaw              string
+	pf               profileFlags
+	// uncertain is whether cda, crr, mr and vw may be distributions.
+	uncertain bool
+}
+
+// register defines the flags on fs, including the yaw flag if yaw.
+func (mf *modelFlags) register(fs *flag.FlagSet, yaw bool) {
+	mf.cda, mf.crr, mf.mr = DistributionFlag{Value: 0.325}, DistributionFlag{Value: calc.Crr}, DistributionFlag{Value: 67.0}
+	fs.Float64Var(&mf.rho, "rho", calc.Rho0, "air density in kg/m*3")
+	fs.Float64Var(&mf.h, "h", 0, "median elevation in m (determines rho)")
+	fs.Var(&mf.cda, "cda", "coefficient of drag area")
+	if yaw {
+		fs.StringVar(&mf.yaw, "yaw", "", "position whose typical CdA curve is used to account for yaw (tops, hoods, drops or tt)")
+	}
+	fs.Var(&mf.crr, "crr", "coefficient of rolling resistance")
+	fs.Var(&mf.mr, "mr", "total mass of the rider in kg")
+	fs.Float64Var(&mf.mb, "mb", 8.0, "total mass of the bicycle in kg")
+	fs.Int64Var(&mf.tire, "tire", 23, "the tire width in mm")
+	fs.Float64Var(&mf.ec, "ec", calc.Ec, "drivetrain efficiency")
+	fs.Var(&mf.vw, "vw", "the wind speed in m/s")
+	fs.Var(&mf.dw, "dw", "the cardinal direction the wind originates from")
+	mf.pf.register(fs, true)
+}
+
+// model verifies the flags and returns the Model they describe, where any
+// uncertain values are replaced by their central value.
+func (mf *modelFlags) model(set map[string]bool) (calc.Model, error) {
+	for _, f := range []struct {
+		name string
+		x    DistributionFlag
+	}{{"cda", mf.cda}, {"crr", mf.crr}, {"mr", mf.mr}, {"vw", mf.vw}} {
+		if f.x.Distribution != nil && !mf.uncertain {
+			return calc.Model{}, fmt.Errorf("%s can't be uncertain", f.name)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		x    float64
+	}{{"rho", mf.rho}, {"h", mf.h}, {"cda", mf.cda.Value}, {"crr", mf.crr.Value}, {"mr", mf.mr.Value}, {"mb", mf.mb}, {"vw", mf.vw.Value}} {
+		if f.x < 0 {
+			return calc.Model{}, fmt.Errorf("%s must be non negative but was %f", f.name, f.x)
+		}
+	}
+	if mf.ec <= 0 || mf.ec > 1 {
+		return calc.Model{}, fmt.Errorf("ec must be in (0, 1] but was %f", mf.ec)
+	}
+
+	r, err := tireRadius(mf.tire)
+	if err != nil {
+		return calc.Model{}, err
+	}
+	rho := mf.rho
+	if mf.h != 0 {
+		r := calc.Rho(mf.h, calc.G)
+		// if both are specified, make sure they agree
+		if rho != calc.Rho0 && r != rho {
+			return calc.Model{}, fmt.Errorf("specified both rho=%f and h=%f but they do not agree", rho, mf.h)
+		}
+		rho = r
+	}
+
+	m := calc.Model{
+		Rider: calc.Rider{Mass: mf.mr.Value, CdA: mf.cda.Value},
+		Bike: calc.Bike{
+			Mass:                 mf.mb,
+			Crr:                  mf.crr.Value,
+			TireRadius:           r,
+			WheelInertia:         calc.I,
+			DrivetrainEfficiency: mf.ec,
+			Fw:                   calc.Fw,
+		},
+		Environment: calc.Environment{
+			Rho:  rho,
+			G:    calc.G,
+			Wind: calc.Wind{Speed: mf.vw.Value, Direction: mf.dw.Direction},
+		},
+	}
+	if mf.yaw != "" {
+		m.Rider.Curve, err = cdaCurve(mf.yaw, mf.cda.Value, set["cda"])
+		if err != nil {
+			return calc.Model{}, err
+		}
+	}
+	return m, nil
+}
+
+// uncertainty returns the Uncertainty of the flags given the distribution of
+// the power p and the number of samples to draw.
+func (mf *modelFlags) uncertainty(p DistributionFlag, samples int) calc.Uncertainty {
+	u := calc.Uncertainty{Variables: make(map[calc.Variable]calc.Distribution), Power: p.Distribution, Samples: samples}
+	for v, df := range map[calc.Variable]DistributionFlag{calc.VarCdA: mf.cda, calc.VarCrr: mf.crr, calc.VarWind: mf.vw} {
+		if df.Distribution != nil {
+			u.Variables[v] = df.Distribution
+		}
+	}
+	if mf.mr.Distribution != nil {
+		u.Variables[calc.VarMass] = offset{mf.mr.Distribution, mf.mb}
+	}
+	return u
+}
+
+// courseFlags are the flags describing the route ridden.
+type courseFlags struct {
+	d, gr, e float64
+	db       DirectionFlag
+	gpx      string
+}
+
+// register defines the flags on fs.
+func (cf *courseFlags) register(fs *flag.FlagSet) {
+	fs.Float64Var(&cf.d, "d", -1, "distance travelled in m")
+	fs.Float64Var(&cf.gr, "gr", 0, "average grade")
+	fs.Float64Var(&cf.e, "e", 0, "total elevation gained in m")
+	fs.Var(&cf.db, "db", "the cardinal direction the bicycle is travelling")
+	fs.StringVar(&cf.gpx, "gpx", "", "GPX file of the route (replaces d, gr, e and db)")
+}
+
+// course verifies the flags and returns the Course they describe, adjusting
+// the air density of m to the elevation of the route unless it was provided.
+func (cf *courseFlags) course(m *calc.Model, mf *modelFlags, set map[string]bool) (calc.Course, error) {
+	if cf.gpx != "" {
+		for _, f := range []string{"d", "gr", "e", "db"} {
+			if set[f] {
+				return nil, fmt.Errorf("%s can't be provided with gpx", f)
+			}
+		}
+		course, err := readCourse(cf.gpx)
+		if err != nil {
+			return nil, err
+		}
+		if mf.h == 0 && !set["rho"] {
+			m.Environment.Rho = 0
+		}
+		return course, nil
+	}
+
+	gr := grade(cf.gr)
+	if cf.d <= 0 {
+		return nil, fmt.Errorf("d must be positive but was %f", cf.d)
+	}
+	if cf.e > 0 {
+		// if both are specified, make sure they agree
+		if gr > 0 && ((cf.d*gr != cf.e) || (cf.e/cf.d != gr)) {
+			return nil, fmt.Errorf("specified both e=%f and gr=%f but they do not agree", cf.e, gr)
+		}
+		gr = cf.e / cf.d
+	}
+	return calc.Course{{Length: cf.d, Grade: gr, Heading: cf.db.Direction, Elevation: mf.h}}, nil
+}
+
+// build returns the Model and Course described by the flags, exiting if they
+// are invalid.
+func build(mf *modelFlags, cf *courseFlags, set map[string]bool) (calc.Model, calc.Course) {
+	m, err := mf.model(set)
+	if err != nil {
+		exit(err)
+	}
+	course, err := cf.course(&m, mf, set)
+	if err != nil {
+		exit(err)
+	}
+	return m, course
+}
+
+// grade corrects the grade gr in case it was passed in as a %.
+func grade(gr float64) float64 {
+	if gr > 1 || gr < -1 {
+		return gr / 100
+	}
+	return gr
+}
+
+// pipe returns whether stdout is not a terminal.
+func pipe() bool {
+	fi, _ := os.Stdout.Stat()
+	return (fi.Mode() & os.ModeCharDevice) == 0
+}
+
+// power prints the constant power required to ride a course in a given time.
+func power(args []string) {
+	var mf modelFlags
+	var cf courseFlags
+	var dur time.Duration
+	var format string
+
+	fs := subcommand("power", "")
+	mf.register(fs, true)
+	cf.register(fs)
+	fs.DurationVar(&dur, "t", -1, "duration in minutes and seconds ('12m34s')")
+	fs.StringVar(&format, "format", "text", "output format (text, json or csv)")
+	set := parse(fs, args)
+
+	m, course := build(&mf, &cf, set)
+	if dur <= 0 {
+		exit(fmt.Errorf("t must be positive but was %s", dur))
+	}
+
+	res, err := course.Power(m, dur.Seconds())
+	if err != nil {
+		exit(fmt.Errorf("unable to calculate power for t=%s: %s", dur, err))
+	}
+	comp := res.Components()
+	switch {
+	case format == "json" || format == "csv":
+		emit(summarize(course, m, res, 0, inputs{Yaw: mf.yaw}), format)
+	case format != "text":
+		exit(fmt.Errorf("invalid format '%s'", format))
+	case pipe():
+		fmt.Println(res.Power)
+	default:
+		fmt.Printf("%s (%.2f km @ %.2f%%) = %.2f W (%.2f W/kg) = AT:%.2f W + RR:%.2f W + WB:%.2f W + PE:%.2f W + KE:%.2f W\n",
+			fmtDuration(dur), course.Distance()/1000, course.Grade()*100, res.Power, res.Power/mf.mr.Value,
+			comp.AT, comp.RR, comp.WB, comp.PE, comp.KE)
+	}
+}
+
+// duration prints the time taken to ride a course at a constant power.
+func duration(args []string) {
+	var mf modelFlags
+	var cf courseFlags
+	var p float64
+	var format string
+
+	fs := subcommand("time", "")
+	mf.register(fs, true)
+	cf.register(fs)
+	fs.Float64Var(&p, "p", -1, "power in watts")
+	fs.StringVar(&format, "format", "text", "output format (text, json or csv)")
+	set := parse(fs, args)
+
+	m, course := build(&mf, &cf, set)
+	if !set["p"] {
+		exit(fmt.Errorf("p must be provided"))
+	}
+	verify("p", p)
+
+	res, err := course.Time(m, p)
+	if err != nil {
+		exit(fmt.Errorf("unable to calculate time for p=%f: %s", p, err))
+	}
+	dur := time.Duration(res.Time * float64(time.Second))
+	switch {
+	case format == "json" || format == "csv":
+		emit(summarize(course, m, res, 0, inputs{Yaw: mf.yaw}), format)
+	case format != "text":
+		exit(fmt.Errorf("invalid format '%s'", format))
+	case pipe():
+		fmt.Println(dur.Round(time.Second))
+	default:
+		fmt.Printf("%.2f km @ %.2f%% @ %.2f W (%.2f W/kg) = %s\n",
+			course.Distance()/1000, course.Grade()*100, p, p/mf.mr.Value, fmtDuration(dur))
+	}
+}
+
+// conditions are the flags describing the road for subcommands which don't
+// ride an entire course.
+type conditions struct {
+	gr float64
+	db DirectionFlag
+}
+
+// register defines the flags on fs.
+func (c *conditions) register(fs *flag.FlagSet) {
+	fs.Float64Var(&c.gr, "gr", 0, "grade")
+	fs.Var(&c.db, "db", "the cardinal direction the bicycle is travelling")
+}
+
+// distance prints the distance ridden in a given time at a constant power.
+func distance(args []string) {
+	var mf modelFlags
+	var c conditions
+	var p float64
+	var dur time.Duration
+
+	fs := subcommand("distance", "")
+	mf.register(fs, false)
+	c.register(fs)
+	fs.Float64Var(&p, "p", -1, "power in watts")
+	fs.DurationVar(&dur, "t", -1, "duration in minutes and seconds ('12m34s')")
+	set := parse(fs, args)
+
+	m, err := mf.model(set)
+	if err != nil {
+		exit(err)
+	}
+	if !set["p"] || dur <= 0 {
+		exit(fmt.Errorf("p and t must both be provided"))
+	}
+	verify("p", p)
+
+	env, b := m.Environment, m.Bike
+	d := calc.D(p, dur.Seconds(), env.Rho, m.Rider.CdA, b.Crr, env.Wind.Speed, env.Wind.Direction,
+		c.db.Direction, grade(c.gr), m.Mass(), env.G, b.DrivetrainEfficiency, b.Fw)
+	if math.IsNaN(d) {
+		exit(fmt.Errorf("unable to calculate distance for p=%f: %s", p, calc.ErrNoSolution))
+	}
+
+	if pipe() {
+		fmt.Printf("-d=%.2f\n", d)
+	} else {
+		fmt.Printf("%s @ %.2f%% @ %.2f W (%.2f W/kg) = %.2f km\n",
+			fmtDuration(dur), grade(c.gr)*100, p, p/mf.mr.Value, d/1000)
+	}
+}
+
+// speed prints the ground velocity at a constant power.
+func speed(args []string) {
+	var mf modelFlags
+	var c conditions
+	var p float64
+
+	fs := subcommand("speed", "")
+	mf.register(fs, false)
+	c.register(fs)
+	fs.Float64Var(&p, "p", -1, "power in watts")
+	set := parse(fs, args)
+
+	m, err := mf.model(set)
+	if err != nil {
+		exit(err)
+	}
+	if !set["p"] {
+		exit(fmt.Errorf("p must be provided"))
+	}
+	verify("p", p)
+
+	env, b := m.Environment, m.Bike
+	vg := calc.Vg(p, env.Rho, m.Rider.CdA, b.Crr, env.Wind.Speed, env.Wind.Direction,
+		c.db.Direction, grade(c.gr), m.Mass(), env.G, b.DrivetrainEfficiency, b.Fw)
+	if math.IsNaN(vg) {
+		exit(fmt.Errorf("unable to calculate speed for p=%f: %s", p, calc.ErrNoSolution))
+	}
+
+	if pipe() {
+		fmt.Println(vg)
+	} else {
+		fmt.Printf("%.2f%% @ %.2f W (%.2f W/kg) = %.2f km/h (%.2f m/s)\n",
+			grade(c.gr)*100, p, p/mf.mr.Value, vg*3.6, vg)
+	}
+}
+
+// density prints the air density at an altitude and in given weather.
+func density(args []string) {
+	var h, temp, pressure, humidity, dewpoint float64
+
+	fs := subcommand("density", "")
+	fs.Float64Var(&h, "h", 0, "elevation in m")
+	fs.Float64Var(&temp, "temp", 15, "air temperature in Celsius")
+	fs.Float64Var(&pressure, "pressure", calc.P0/100.0, "barometric pressure in hPa")
+	fs.Float64Var(&humidity, "humidity", 0, "relative humidity in % (0 to 100)")
+	fs.Float64Var(&dewpoint, "dewpoint", 0, "dew point in Celsius (replaces humidity)")
+	set := parse(fs, args)
+
+	verify("h", h)
+	verify("pressure", pressure)
+	verify("humidity", humidity)
+	if set["dewpoint"] {
+		if set["humidity"] {
+			exit(fmt.Errorf("humidity can't be provided with dewpoint"))
+		}
+		if !set["temp"] {
+			exit(fmt.Errorf("temp must be provided with dewpoint"))
+		}
+		humidity = calc.RelativeHumidity(temp, dewpoint) * 100
+	}
+	if humidity > 100 {
+		exit(fmt.Errorf("humidity must be at most 100%% but was %f", humidity))
+	}
+
+	rho := calc.Rho(h, calc.G)
+	if set["temp"] || set["pressure"] || set["humidity"] || set["dewpoint"] {
+		rho = airDensity(h, temp, pressure, humidity, set["temp"], set["pressure"])
+	}
+
+	if pipe() {
+		fmt.Printf("-rho=%.4f\n", rho)
+	} else {
+		t := temp
+		if !set["temp"] {
+			t = calc.T0 - calc.L*h - calc.K
+		}
+		pa := pressure * 100
+		if !set["pressure"] {
+			pa = calc.AirPressure(h, t)
+		}
+		fmt.Printf("-rho=%.4f (%.0f m @ %.1f °C @ %.2f hPa @ %.0f%%)\n", rho, h, t, pa/100, humidity)
+	}
+}
+
+// cda prints the typical CdA of a rider given their height and mass.
+func cda(args []string) {
+	var height, mr float64
+	var position string
+
+	fs := subcommand("cda", "")
+	fs.Float64Var(&height, "height", 1.75, "height of the rider in m")
+	fs.Float64Var(&mr, "mr", 67.0, "total mass of the rider in kg")
+	fs.StringVar(&position, "position", "drops", "position of the rider (drops or aero)")
+	var pf profileFlags
+	pf.register(fs, false)
+	parse(fs, args)
+
+	verify("height", height)
+	verify("mr", mr)
+	// error correct in case height was passed in as cm
+	if height > 3 {
+		height = height / 100
+	}
+
+	var cda, a float64
+	switch strings.ToLower(position) {
+	case "drops":
+		cda, a = calc.CalculateDropsCdA(height, mr), calc.DropsA(height, mr)
+	case "aero":
+		cda, a = calc.CalculateAeroCdA(height, mr), calc.AeroA(height, mr)
+	default:
+		exit(fmt.Errorf("invalid position '%s'", position))
+	}
+
+	if pipe() {
+		fmt.Printf("-cda=%.4f\n", cda)
+	} else {
+		fmt.Printf("-cda=%.4f (%.2f m, %.2f kg, A %.4f m²)\n", cda, height, mr, a)
+	}
+}
+
+// altitude prints the power at an altitude equivalent to a power at sea level.
+func altitude(args []string) {
+	var p, h float64
+
+	fs := subcommand("altitude", "")
+	fs.Float64Var(&p, "p", -1, "sustainable power at sea level in watts")
+	fs.Float64Var(&h, "h", 0, "elevation in m")
+	set := parse(fs, args)
+
+	if !set["p"] {
+		exit(fmt.Errorf("p must be provided"))
+	}
+	verify("p", p)
+	verify("h", h)
+
+	pa := calc.AltitudeAdjust(p, h)
+	if pipe() {
+		fmt.Printf("-p=%.2f\n", pa)
+	} else {
+		fmt.Printf("-p=%.2f (%.2f W @ %.0f m, %.1f%%)\n", pa, p, h, (pa/p-1)*100)
+	}
+}
+
+// mmp prints the mean maximal power curve and estimated FTP of the FIT or CSV
+// file of power provided as the argument to the 'mmp' subcommand.
+func mmp(args []string) {
+	var mr float64
+	var method string
+
+	fs := subcommand("mmp", " <file>")
+	fs.Float64Var(&mr, "mr", 67.0, "total mass of the rider in kg")
+	fs.StringVar(&method, "method", "20min", "method of estimating FTP (20min or curve)")
+	fs.Parse(args)
+
+	verify("mr", mr)
+	methods := map[string]calc.FTPMethod{"20min": calc.TwentyMinute, "curve": calc.CurveFit}
+	m, ok := methods[strings.ToLower(method)]
+	if !ok {
+		exit(fmt.Errorf("invalid ftp method '%s'", method))
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	power, err := readPower(fs.Arg(0))
+	if err != nil {
+		exit(err)
+	}
+	curve := calc.MeanMaximalPower(power, 1, nil)
+	ftp, err := curve.FTP(m)
+	if err != nil {
+		exit(fmt.Errorf("unable to estimate ftp: %s", err))
+	}
+
+	if pipe() {
+		fmt.Printf("-p=%.2f\n", ftp)
+		return
+	}
+	for _, e := range curve.Efforts(calc.EffortDurations) {
+		fmt.Printf("%8s = %.2f W (%.2f W/kg)\n",
+			fmtDuration(time.Duration(e.Duration)*time.Second), e.Power, e.Power/mr)
+	}
+	fmt.Printf("     FTP = %.2f W (%.2f W/kg)\n", ftp, ftp/mr)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scheibo/calc"
+)
+
+// RADII maps from tire width to radius
+var RADII = map[int64]float64{
+	20: calc.R700x20,
+	22: calc.R700x22,
+	23: calc.R700x23,
+	25: calc.R700x25,
+	28: calc.R700x28,
+}
+
+// CURVES maps from position to the typical CdA curve for that position
+var CURVES = map[string]calc.CdACurve{
+	"tops":  calc.TopsCdACurve,
+	"hoods": calc.HoodsCdACurve,
+	"drops": calc.DropsCdACurve,
+	"tt":    calc.TTAeroCdACurve,
+}
+
+// COMPASS maps from cardinal direction to degrees
+var COMPASS = map[string]float64{
+	"N":   0,
+	"NNE": 22.5,
+	"NE":  45,
+	"ENE": 67.5,
+	"E":   90,
+	"ESE": 122.5,
+	"SE":  135,
+	"SSE": 157.5,
+	"S":   180,
+	"SSW": 202.5,
+	"SW":  225,
+	"WSW": 247.5,
+	"W":   270,
+	"WNW": 292.5,
+	"NW":  315,
+	"NNW": 337.5,
+}
+
+type DirectionFlag struct {
+	Direction float64
+}
+
+func (df *DirectionFlag) String() string {
+	return strconv.FormatFloat(df.Direction, 'f', -1, 64)
+}
+
+func (df *DirectionFlag) Set(v string) error {
+	d, ok := COMPASS[strings.ToUpper(v)]
+	if ok {
+		df.Direction = d
+		return nil
+	}
+
+	d, err := strconv.ParseFloat(v, 64)
+	if err == nil {
+		df.Direction = d
+		return nil
+	}
+
+	return fmt.Errorf("invalid direction '%s'", v)
+}
+
+// DistributionFlag is a value which may be uncertain, specified as either 'x',
+// 'x±sd' (or 'x+-sd') for a normal distribution, 'min~max' for a uniform
+// distribution or 'min~mode~max' for a triangular distribution.
+type DistributionFlag struct {
+	Value        float64
+	Distribution calc.Distribution
+}
+
+func (df *DistributionFlag) String() string {
+	switch d := df.Distribution.(type) {
+	case calc.Normal:
+		return fmt.Sprintf("%g±%g", d.Mean, d.StdDev)
+	case calc.Uniform:
+		return fmt.Sprintf("%g~%g", d.Min, d.Max)
+	case calc.Triangular:
+		return fmt.Sprintf("%g~%g~%g", d.Min, d.Mode, d.Max)
+	}
+	return strconv.FormatFloat(df.Value, 'f', -1, 64)
+}
+
+func (df *DistributionFlag) Set(v string) error {
+	var xs []float64
+	sep := "~"
+	if strings.Contains(v, "±") || strings.Contains(v, "+-") {
+		sep = "±"
+	}
+	for _, f := range strings.Split(strings.Replace(v, "+-", "±", 1), sep) {
+		x, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s'", v)
+		}
+		xs = append(xs, x)
+	}
+
+	switch {
+	case len(xs) == 1:
+		df.Value, df.Distribution = xs[0], nil
+	case sep == "±" && len(xs) == 2 && xs[1] >= 0:
+		df.Value, df.Distribution = xs[0], calc.Normal{Mean: xs[0], StdDev: xs[1]}
+	case len(xs) == 2 && xs[0] <= xs[1]:
+		df.Value, df.Distribution = (xs[0]+xs[1])/2, calc.Uniform{Min: xs[0], Max: xs[1]}
+	case len(xs) == 3 && xs[0] <= xs[1] && xs[1] <= xs[2]:
+		df.Value, df.Distribution = xs[1], calc.Triangular{Min: xs[0], Mode: xs[1], Max: xs[2]}
+	default:
+		return fmt.Errorf("invalid distribution '%s'", v)
+	}
+	return nil
+}
+
+// offset is a Distribution shifted by a constant.
+type offset struct {
+	calc.Distribution
+	x float64
+}
+
+func (o offset) Sample(r *rand.Rand) float64 {
+	return o.Distribution.Sample(r) + o.x
+}
+
+func fmtDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// cdaCurve returns the CdA curve for the position, scaled to cda if scale.
+func cdaCurve(position string, cda float64, scale bool) (calc.CdACurve, error) {
+	c, ok := CURVES[strings.ToLower(position)]
+	if !ok {
+		return nil, fmt.Errorf("invalid position '%s'", position)
+	}
+	if scale {
+		return calc.ScaleCdA(c, cda), nil
+	}
+	return c, nil
+}
+
+func tireRadius(tire int64) (float64, error) {
+	r, ok := RADII[tire]
+	if !ok {
+		return 0, fmt.Errorf("invalid tire width '%d'", tire)
+	}
+	return r, nil
+}
+
+func verify(s string, x float64) {
+	if x < 0 {
+		exit(fmt.Errorf("%s must be non negative but was %f", s, x))
+	}
+}
+
+// usage prints the defaults of the flags of the command being run.
+var usage = flag.PrintDefaults
+
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	usage()
+	os.Exit(1)
+}
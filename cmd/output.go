@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/scheibo/calc"
+)
+
+// inputs are the values of every input to a calculation after they have been
+// resolved from the flags (e.g. rho from h or gr from e).
+type inputs struct {
+	Rho float64 `json:"rho"`
+	// CdA is the average CdA over the performance if it depends on the yaw
+	// angle, in which case Yaw is the position of the CdA curve.
+	CdA        float64 `json:"cda"`
+	Yaw        string  `json:"yaw,omitempty"`
+	Crr        float64 `json:"crr"`
+	Mr         float64 `json:"mr"`
+	Mb         float64 `json:"mb"`
+	Mt         float64 `json:"mt"`
+	TireRadius float64 `json:"r"`
+	Ec         float64 `json:"ec"`
+	Fw         float64 `json:"fw"`
+	Vw         float64 `json:"vw"`
+	Dw         float64 `json:"dw"`
+	// Db is the direction of travel, unless it varies over the course.
+	Db        *float64 `json:"db,omitempty"`
+	Distance  float64  `json:"d"`
+	Grade     float64  `json:"gr"`
+	Elevation float64  `json:"h"`
+	weather
+}
+
+// weather is the temperature in Celsius, pressure in hPa and relative humidity
+// in % the air density was calculated from, if they were provided.
+type weather struct {
+	Temp     *float64 `json:"temp,omitempty"`
+	Pressure *float64 `json:"pressure,omitempty"`
+	Humidity *float64 `json:"humidity,omitempty"`
+}
+
+// components is the breakdown of the power in watts required.
+type components struct {
+	AT float64 `json:"at"`
+	RR float64 `json:"rr"`
+	WB float64 `json:"wb"`
+	PE float64 `json:"pe"`
+	KE float64 `json:"ke"`
+}
+
+// training is the training load of a performance, if an FTP was provided.
+type training struct {
+	NP  float64 `json:"np"`
+	IF  float64 `json:"if"`
+	TSS float64 `json:"tss"`
+	VI  float64 `json:"vi"`
+}
+
+// summary is the structured result of a calculation.
+type summary struct {
+	Inputs     inputs     `json:"inputs"`
+	Time       float64    `json:"t"`
+	Duration   string     `json:"duration"`
+	Power      float64    `json:"p"`
+	WKg        float64    `json:"wkg"`
+	Speed      float64    `json:"vg"`
+	Components components `json:"components"`
+	Load       *training  `json:"load,omitempty"`
+}
+
+// summarize returns the summary of the performance res of m riding the course,
+// where in are the inputs which are only known to the caller (the weather and
+// the position of the CdA curve).
+func summarize(course calc.Course, m calc.Model, res calc.Result, ftp float64, in inputs) summary {
+	rho := m.Environment.Rho
+	if rho == 0 {
+		rho = calc.Rho(course.Elevation(), m.Environment.G)
+	}
+	cda := m.Rider.CdA
+	if m.Rider.Curve != nil && res.Time > 0 {
+		cda = 0
+		for _, s := range res.Splits {
+			cda += m.CdA(s.Speed, s.Conditions()) * s.Time / res.Time
+		}
+	}
+	in.Db = nil
+	if len(course) == 1 {
+		in.Db = &course[0].Heading
+	}
+	comp := res.Components()
+	s := summary{
+		Inputs: inputs{
+			Rho:        rho,
+			CdA:        cda,
+			Yaw:        in.Yaw,
+			Crr:        m.Bike.Crr,
+			Mr:         m.Rider.Mass,
+			Mb:         m.Bike.Mass,
+			Mt:         m.Mass(),
+			TireRadius: m.Bike.TireRadius,
+			Ec:         m.Bike.DrivetrainEfficiency,
+			Fw:         m.Bike.Fw,
+			Vw:         m.Environment.Wind.Speed,
+			Dw:         m.Environment.Wind.Direction,
+			Db:         in.Db,
+			Distance:   course.Distance(),
+			Grade:      course.Grade(),
+			Elevation:  course.Elevation(),
+			weather:    in.weather,
+		},
+		Time:       res.Time,
+		Duration:   time.Duration(res.Time * float64(time.Second)).Round(time.Second).String(),
+		Power:      res.Power,
+		WKg:        res.Power / m.Rider.Mass,
+		Speed:      res.Speed(),
+		Components: components{AT: comp.AT, RR: comp.RR, WB: comp.WB, PE: comp.PE, KE: comp.KE},
+	}
+	if ftp > 0 {
+		l, err := calc.TrainingLoad(res.PowerSeries(1), 1, ftp)
+		if err != nil {
+			exit(err)
+		}
+		s.Load = &training{NP: l.NP, IF: l.IF, TSS: l.TSS, VI: l.VI}
+	}
+	return s
+}
+
+// fields returns the names and values of each field of the summary, flattened
+// into columns.
+func (s summary) fields() ([]string, []string) {
+	f := func(x float64) string {
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	// optional values are empty if not provided
+	o := func(x *float64) string {
+		if x == nil {
+			return ""
+		}
+		return f(*x)
+	}
+	in, comp := s.Inputs, s.Components
+	names := []string{
+		"rho", "cda", "yaw", "crr", "mr", "mb", "mt", "r", "ec", "fw", "vw", "dw", "db", "d", "gr", "h",
+		"temp", "pressure", "humidity", "t", "duration", "p", "wkg", "vg", "at", "rr", "wb", "pe", "ke",
+	}
+	values := []string{
+		f(in.Rho), f(in.CdA), in.Yaw, f(in.Crr), f(in.Mr), f(in.Mb), f(in.Mt), f(in.TireRadius), f(in.Ec),
+		f(in.Fw), f(in.Vw), f(in.Dw), o(in.Db), f(in.Distance), f(in.Grade), f(in.Elevation),
+		o(in.Temp), o(in.Pressure), o(in.Humidity),
+		f(s.Time), s.Duration, f(s.Power), f(s.WKg), f(s.Speed),
+		f(comp.AT), f(comp.RR), f(comp.WB), f(comp.PE), f(comp.KE),
+	}
+	if s.Load != nil {
+		names = append(names, "np", "if", "tss", "vi")
+		values = append(values, f(s.Load.NP), f(s.Load.IF), f(s.Load.TSS), f(s.Load.VI))
+	}
+	return names, values
+}
+
+// write writes the summary to w in the format (json or csv).
+func (s summary) write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case "csv":
+		names, values := s.fields()
+		cw := csv.NewWriter(w)
+		cw.WriteAll([][]string{names, values})
+		return cw.Error()
+	default:
+		return fmt.Errorf("invalid format '%s'", format)
+	}
+}
+
+// emit prints the summary in the format or exits if it can't be written.
+func emit(s summary, format string) {
+	if err := s.write(os.Stdout, format); err != nil {
+		exit(err)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/scheibo/calc"
+)
+
+// profileFlags are the flags selecting the rider and bike profiles.
+type profileFlags struct {
+	rider, bike, position string
+}
+
+// register defines the flags on fs, including the position flag if position.
+func (pf *profileFlags) register(fs *flag.FlagSet, position bool) {
+	fs.StringVar(&pf.rider, "rider", "", "name of the rider profile to use for mr, cda, ftp and height")
+	fs.StringVar(&pf.bike, "bike", "", "name of the bike profile to use for mb, tire, crr and ec")
+	if position {
+		fs.StringVar(&pf.position, "position", "",
+			"position of the rider profile whose CdA is used (defaults to the profile's position, or yaw)")
+	}
+}
+
+// profiles sets each flag of fs which was not explicitly set to the value from
+// the selected rider and bike profiles, so that flags override profiles. The
+// flags which were set from the profiles are returned.
+func profiles(fs *flag.FlagSet, set map[string]bool) (map[string]bool, error) {
+	lookup := func(name string) string {
+		if f := fs.Lookup(name); f != nil {
+			return f.Value.String()
+		}
+		return ""
+	}
+	profiled := make(map[string]bool)
+	rider, bike := lookup("rider"), lookup("bike")
+	if rider == "" && bike == "" {
+		return profiled, nil
+	}
+
+	ps, err := calc.LoadProfiles("")
+	if err != nil {
+		return profiled, err
+	}
+	values := make(map[string]float64)
+	if rider != "" {
+		r, err := ps.Rider(rider)
+		if err != nil {
+			return profiled, err
+		}
+		values["mr"], values["height"], values["ftp"] = r.Mass, r.Height, r.FTP
+		if len(r.CdA) > 0 && fs.Lookup("cda") != nil && !set["cda"] {
+			// the position of the yaw curve is only used if the profile has no
+			// default position of its own
+			position := lookup("position")
+			if _, ok := r.CdA[lookup("yaw")]; ok && position == "" && r.Position == "" {
+				position = lookup("yaw")
+			}
+			if values["cda"], err = r.PositionCdA(position); err != nil {
+				return profiled, fmt.Errorf("rider profile '%s': %s", rider, err)
+			}
+		}
+	}
+	if bike != "" {
+		b, err := ps.Bike(bike)
+		if err != nil {
+			return profiled, err
+		}
+		values["mb"], values["tire"], values["crr"], values["ec"] = b.Mass, float64(b.Tire), b.Crr, b.DrivetrainEfficiency
+	}
+
+	for name, x := range values {
+		if x == 0 || set[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, strconv.FormatFloat(x, 'f', -1, 64)); err != nil {
+			return profiled, fmt.Errorf("invalid %s in profile: %s", name, err)
+		}
+		set[name], profiled[name] = true, true
+	}
+	return profiled, nil
+}
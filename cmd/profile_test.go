@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testProfiles = `{
+  "riders": {
+    "alice": {"mass": 60, "height": 1.68, "ftp": 250, "cda": {"hoods": 0.32, "tt": 0.22}, "position": "hoods"},
+    "bob": {"mass": 75, "cda": {"drops": 0.3, "tt": 0.25}}
+  },
+  "bikes": {
+    "tt": {"mass": 8.5, "tire": 25, "crr": 0.0035, "ec": 0.98}
+  }
+}`
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	defer os.Setenv("XDG_CONFIG_HOME", xdg)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "calc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "calc", "profiles.json"), []byte(testProfiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args             []string
+		mr, cda, mb, crr float64
+		tire             int64
+	}{
+		{[]string{}, 67, 0.325, 8, 0.004, 23},
+		{[]string{"-rider", "alice", "-bike", "tt"}, 60, 0.32, 8.5, 0.0035, 25},
+		{[]string{"-rider", "alice", "-bike", "tt", "-mr", "70", "-cda", "0.3", "-tire", "23"}, 70, 0.3, 8.5, 0.0035, 23},
+		{[]string{"-mb", "7", "-crr", "0.005", "-bike", "tt"}, 67, 0.325, 7, 0.005, 25},
+		{[]string{"-rider", "alice", "-position", "tt"}, 60, 0.22, 8, 0.004, 23},
+		// the position of the profile takes precedence over the yaw curve
+		{[]string{"-rider", "alice", "-yaw", "tt"}, 60, 0.32, 8, 0.004, 23},
+		{[]string{"-rider", "bob", "-yaw", "tt"}, 75, 0.25, 8, 0.004, 23},
+		{[]string{"-rider", "bob", "-yaw", "tt", "-position", "drops"}, 75, 0.3, 8, 0.004, 23},
+		{[]string{"-rider", "bob", "-yaw", "tops", "-cda", "0.35"}, 75, 0.35, 8, 0.004, 23},
+	}
+	for _, tt := range tests {
+		var mf modelFlags
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		mf.register(fs, true)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("Parse(%v): got: %v", tt.args, err)
+		}
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if _, err := profiles(fs, set); err != nil {
+			t.Errorf("profiles(%v): got: %v", tt.args, err)
+			continue
+		}
+		if mf.mr.Value != tt.mr || mf.cda.Value != tt.cda || mf.mb != tt.mb || mf.crr.Value != tt.crr || mf.tire != tt.tire {
+			t.Errorf("profiles(%v): got: mr=%g cda=%g mb=%g crr=%g tire=%d, want: mr=%g cda=%g mb=%g crr=%g tire=%d",
+				tt.args, mf.mr.Value, mf.cda.Value, mf.mb, mf.crr.Value, mf.tire, tt.mr, tt.cda, tt.mb, tt.crr, tt.tire)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-rider", "carol"},
+		{"-bike", "mtb"},
+		// bob has more than one position and no default
+		{"-rider", "bob"},
+		{"-rider", "alice", "-position", "tops"},
+	} {
+		var mf modelFlags
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		mf.register(fs, true)
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("Parse(%v): got: %v", args, err)
+		}
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if _, err := profiles(fs, set); err == nil {
+			t.Errorf("profiles(%v): got: nil, want: error", args)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scheibo/calc"
+)
+
+// state is the inputs of a REPL session which can be saved as a baseline.
+type state struct {
+	mf  modelFlags
+	cf  courseFlags
+	p   float64
+	t   time.Duration
+	set map[string]bool
+	// profiled are the flags which were set from the rider and bike profiles.
+	profiled map[string]bool
+	// target is what is solved for after each change, either 'time' or 'power'.
+	target string
+}
+
+// session is an interactive REPL session where each line is a command which
+// changes or solves for the current state.
+type session struct {
+	state
+	fs *flag.FlagSet
+	// result is the result of the most recent solve, if any.
+	result *calc.Result
+	// base is the saved baseline state and baseline is its result, if any.
+	base     *state
+	baseline *calc.Result
+}
+
+// repl starts an interactive session reading commands from stdin, where the
+// initial state is given by the flags in args.
+func repl(args []string) {
+	s := &session{}
+	s.fs = subcommand("repl", "")
+	s.mf.register(s.fs, true)
+	s.cf.register(s.fs)
+	s.fs.Float64Var(&s.p, "p", -1, "power in watts")
+	s.fs.DurationVar(&s.t, "t", -1, "duration in minutes and seconds ('12m34s')")
+	s.fs.Parse(args)
+	s.set = make(map[string]bool)
+	s.fs.Visit(func(f *flag.Flag) { s.set[f.Name] = true })
+	if err := s.profile(); err != nil {
+		exit(err)
+	}
+	s.target = "time"
+	if s.set["t"] && !s.set["p"] {
+		s.target = "power"
+	}
+
+	fi, _ := os.Stdin.Stat()
+	s.run(os.Stdin, os.Stdout, (fi.Mode()&os.ModeCharDevice) != 0)
+}
+
+// run executes the commands read from r and writes the output to w, prompting
+// for each command if interactive.
+func (s *session) run(r io.Reader, w io.Writer, interactive bool) {
+	if interactive {
+		fmt.Fprintf(w, "Type 'help' for a list of commands.\n> ")
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			if err := s.exec(w, strings.Fields(line)); err == io.EOF {
+				return
+			} else if err != nil {
+				fmt.Fprintf(w, "error: %s\n", err)
+			}
+		}
+		if interactive {
+			fmt.Fprintf(w, "> ")
+		}
+	}
+}
+
+// exec executes a single command, returning io.EOF if the session should end.
+func (s *session) exec(w io.Writer, cmd []string) error {
+	switch cmd[0] {
+	case "set":
+		if len(cmd) == 2 && strings.Contains(cmd[1], "=") {
+			cmd = append([]string{"set"}, strings.SplitN(cmd[1], "=", 2)...)
+		}
+		if len(cmd) != 3 {
+			return fmt.Errorf("usage: set <flag> <value>")
+		}
+		name := strings.TrimPrefix(cmd[1], "-")
+		prev, wasSet := "", s.set[name]
+		if f := s.fs.Lookup(name); f != nil {
+			prev = f.Value.String()
+		}
+		if err := s.fs.Set(name, cmd[2]); err != nil {
+			return err
+		}
+		s.set[name] = true
+		delete(s.profiled, name)
+		switch name {
+		// the profiles (and the position used) depend on these flags
+		case "rider", "bike", "position", "yaw":
+			if err := s.profile(); err != nil {
+				s.fs.Set(name, prev)
+				s.set[name] = wasSet
+				s.profile()
+				return err
+			}
+		}
+		switch name {
+		case "p":
+			s.target = "time"
+		case "t":
+			s.target = "power"
+		}
+		if !s.ready() {
+			return nil
+		}
+		return s.solve(w)
+	case "solve":
+		if len(cmd) > 2 {
+			return fmt.Errorf("usage: solve [time|power|<variable>]")
+		}
+		if len(cmd) == 2 && cmd[1] != "time" && cmd[1] != "power" {
+			return s.variable(w, cmd[1])
+		}
+		if len(cmd) == 2 {
+			s.target = cmd[1]
+		}
+		if !s.ready() {
+			return fmt.Errorf("%s must be set to solve for %s", map[string]string{"time": "p", "power": "t"}[s.target], s.target)
+		}
+		return s.solve(w)
+	case "baseline":
+		if s.result == nil {
+			if !s.ready() {
+				return fmt.Errorf("p or t must be set to save a baseline")
+			}
+			if err := s.solve(w); err != nil {
+				return err
+			}
+		}
+		base := s.state.copy()
+		s.base, s.baseline = &base, s.result
+		fmt.Fprintf(w, "saved baseline\n")
+	case "compare":
+		if s.base == nil {
+			return fmt.Errorf("no baseline has been saved")
+		}
+		course, res, err := s.base.evaluate()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "baseline: ")
+		describe(w, *s.base, course, res, nil)
+		return s.solve(w)
+	case "reset":
+		if s.base == nil {
+			return fmt.Errorf("no baseline has been saved")
+		}
+		s.state = s.base.copy()
+		return s.solve(w)
+	case "show":
+		s.fs.VisitAll(func(f *flag.Flag) {
+			if s.set[f.Name] {
+				fmt.Fprintf(w, "-%s=%s ", f.Name, f.Value)
+			}
+		})
+		fmt.Fprintf(w, "\n")
+	case "help":
+		fmt.Fprintf(w, "set <flag> <value>   change an input, e.g. 'set cda 0.29' or 'set dw=NE'\n")
+		fmt.Fprintf(w, "solve [time|power]   solve for the time given p or the power given t\n")
+		fmt.Fprintf(w, "solve <variable>     solve for cda, crr, mt, gr, vw, rho or ec given p and t\n")
+		fmt.Fprintf(w, "baseline             save the current inputs to compare against\n")
+		fmt.Fprintf(w, "compare              compare the current inputs against the baseline\n")
+		fmt.Fprintf(w, "reset                restore the inputs of the baseline\n")
+		fmt.Fprintf(w, "show                 print the inputs which have been set\n")
+		fmt.Fprintf(w, "quit                 end the session\n\n")
+		fmt.Fprintf(w, "Flags:\n")
+		s.fs.SetOutput(w)
+		s.fs.PrintDefaults()
+		s.fs.SetOutput(nil)
+	case "quit", "exit":
+		return io.EOF
+	default:
+		return fmt.Errorf("unknown command '%s'", cmd[0])
+	}
+	return nil
+}
+
+// ready returns whether the input required to solve for the target is set.
+func (s *session) ready() bool {
+	if s.target == "power" {
+		return s.set["t"]
+	}
+	return s.set["p"]
+}
+
+// profile applies the rider and bike profiles to the flags which were not set
+// explicitly, first restoring the defaults of those set from the previous
+// profiles.
+func (s *session) profile() error {
+	for name := range s.profiled {
+		f := s.fs.Lookup(name)
+		f.Value.Set(f.DefValue)
+		delete(s.set, name)
+	}
+	var err error
+	s.profiled, err = profiles(s.fs, s.set)
+	return err
+}
+
+// copy returns a copy of the state which doesn't share the set flags.
+func (st state) copy() state {
+	set, profiled := make(map[string]bool, len(st.set)), make(map[string]bool, len(st.profiled))
+	for k, v := range st.set {
+		set[k] = v
+	}
+	for k, v := range st.profiled {
+		profiled[k] = v
+	}
+	st.set, st.profiled = set, profiled
+	return st
+}
+
+// evaluate returns the course described by the state st and the result of
+// solving for its target.
+func (st state) evaluate() (calc.Course, calc.Result, error) {
+	m, err := st.mf.model(st.set)
+	if err != nil {
+		return nil, calc.Result{}, err
+	}
+	course, err := st.cf.course(&m, &st.mf, st.set)
+	if err != nil {
+		return nil, calc.Result{}, err
+	}
+
+	var res calc.Result
+	if st.target == "power" {
+		if st.t <= 0 {
+			return nil, calc.Result{}, fmt.Errorf("t must be positive but was %s", st.t)
+		}
+		res, err = course.Power(m, st.t.Seconds())
+	} else {
+		if st.p < 0 {
+			return nil, calc.Result{}, fmt.Errorf("p must be non negative but was %f", st.p)
+		}
+		res, err = course.Time(m, st.p)
+	}
+	if err != nil {
+		return nil, calc.Result{}, fmt.Errorf("unable to calculate %s: %s", st.target, err)
+	}
+	return course, res, nil
+}
+
+// solve solves for the target of the current state and prints the result along
+// with the change relative to the baseline (or the previous result).
+func (s *session) solve(w io.Writer) error {
+	course, res, err := s.state.evaluate()
+	if err != nil {
+		return err
+	}
+	prev := s.result
+	if s.baseline != nil {
+		prev = s.baseline
+	}
+	s.result = &res
+	describe(w, s.state, course, res, prev)
+	return nil
+}
+
+// describe prints the result res of riding the course given the state st and
+// the change relative to prev if it is not nil.
+func describe(w io.Writer, st state, course calc.Course, res calc.Result, prev *calc.Result) {
+	dur := time.Duration(res.Time * float64(time.Second))
+	fmt.Fprintf(w, "%.2f km @ %.2f%% @ %.2f W (%.2f W/kg) = %s",
+		res.Distance/1000, course.Grade()*100, res.Power, res.Power/st.mf.mr.Value, fmtDuration(dur))
+	if prev != nil {
+		fmt.Fprintf(w, " (%s, %+.2f W)", fmtDelta(res.Time-prev.Time), res.Power-prev.Power)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// variable solves for the named variable given both p and t.
+func (s *session) variable(w io.Writer, name string) error {
+	v, err := calc.ParseVariable(name)
+	if err != nil {
+		return err
+	}
+	if !s.set["p"] || !s.set["t"] {
+		return fmt.Errorf("p and t must both be set to solve for %s", v)
+	}
+	m, err := s.mf.model(s.set)
+	if err != nil {
+		return err
+	}
+	course, err := s.cf.course(&m, &s.mf, s.set)
+	if err != nil {
+		return err
+	}
+	out := calc.OutputTime
+	if s.target == "power" {
+		out = calc.OutputPower
+	}
+	x, err := course.Solve(m, v, s.p, s.t.Seconds(), out)
+	if err != nil {
+		return fmt.Errorf("unable to solve for %s: %s", v, err)
+	}
+	if v == calc.VarMass {
+		name, x = "mr", x-s.mf.mb
+	}
+	fmt.Fprintf(w, "-%s=%.5g\n", name, x)
+	return nil
+}
+
+// fmtDelta formats a change in time of dt seconds with its sign.
+func fmtDelta(dt float64) string {
+	sign := "+"
+	if dt < 0 {
+		sign = "-"
+	}
+	return sign + fmtDuration(time.Duration(math.Abs(dt)*float64(time.Second)))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/scheibo/calc"
+)
+
+// newTestSession returns a session with the initial state given by the flags
+// in args, as repl would construct it.
+func newTestSession(t *testing.T, args ...string) *session {
+	s := &session{}
+	s.fs = flag.NewFlagSet("repl", flag.ContinueOnError)
+	s.mf.register(s.fs, true)
+	s.cf.register(s.fs)
+	s.fs.Float64Var(&s.p, "p", -1, "power in watts")
+	s.fs.DurationVar(&s.t, "t", -1, "duration in minutes and seconds ('12m34s')")
+	if err := s.fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	s.set = make(map[string]bool)
+	s.fs.Visit(func(f *flag.Flag) { s.set[f.Name] = true })
+	if err := s.profile(); err != nil {
+		t.Fatal(err)
+	}
+	s.target = "time"
+	return s
+}
+
+func TestSession(t *testing.T) {
+	s := newTestSession(t, "-d", "10000")
+
+	var w bytes.Buffer
+	s.run(strings.NewReader("set p 250\nbaseline\nset cda 0.29\ncompare\nreset\n"), &w, false)
+	want := []string{
+		"10.00 km @ 0.00% @ 250.00 W (3.73 W/kg) = 16:23",
+		"saved baseline",
+		"10.00 km @ 0.00% @ 250.00 W (3.73 W/kg) = 15:49 (-0:34, +0.00 W)",
+		"baseline: 10.00 km @ 0.00% @ 250.00 W (3.73 W/kg) = 16:23",
+		"10.00 km @ 0.00% @ 250.00 W (3.73 W/kg) = 15:49 (-0:34, +0.00 W)",
+		"10.00 km @ 0.00% @ 250.00 W (3.73 W/kg) = 16:23 (+0:00, +0.00 W)",
+	}
+	got := strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got: %q, want: %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got: %q, want: %q", i+1, got[i], want[i])
+		}
+	}
+
+	// reset restores the flags of the baseline
+	if s.mf.cda.Value != 0.325 || s.set["cda"] || !s.set["p"] || s.p != 250 {
+		t.Errorf("reset: got: cda=%g (set %t) p=%g (set %t), want: cda=0.325 (set false) p=250 (set true)",
+			s.mf.cda.Value, s.set["cda"], s.p, s.set["p"])
+	}
+
+	w.Reset()
+	s.run(strings.NewReader("solve cda\nset t 16m23s\nsolve cda\n"), &w, false)
+	got = strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n")
+	if len(got) != 3 || got[0] != "error: p and t must both be set to solve for cda" || !strings.HasPrefix(got[2], "-cda=") {
+		t.Fatalf("solve cda: got: %q", got)
+	}
+	cda, err := strconv.ParseFloat(strings.TrimPrefix(got[2], "-cda="), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the baseline time is rounded to the nearest second
+	if !calc.Eqf(cda, 0.325, 1e-3) {
+		t.Errorf("solve cda: got: %g, want: 0.325", cda)
+	}
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/scheibo/calc"
+)
+
+// maxBody is the maximum size in bytes of a request body.
+const maxBody = 1 << 20
+
+// param is a numeric field of the request or response of an endpoint.
+type param struct {
+	name        string
+	description string
+	// def is the default value of the field, or NaN if it is required.
+	def float64
+	// min and max are the inclusive bounds of the field, where min is
+	// exclusive if positive.
+	min, max float64
+	positive bool
+	// ref is the name of the field whose value is the default, if any.
+	ref string
+}
+
+// apiError is a structured error response.
+type apiError struct {
+	status  int
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// endpoint is a REST endpoint which computes results from the fields of a JSON
+// object POSTed to it.
+type endpoint struct {
+	path    string
+	summary string
+	params  []param
+	results []param
+	// compute returns the results given the values of each of the params and
+	// which were set in the request.
+	compute func(v map[string]float64, set map[string]bool) (map[string]float64, error)
+}
+
+var (
+	inf     = math.Inf(1)
+	req     = math.NaN()
+	rhoP    = param{"rho", "air density in kg/m*3", calc.Rho0, 0, inf, false, ""}
+	cdaP    = param{"cda", "coefficient of drag area in m²", 0.325, 0, inf, false, ""}
+	crrP    = param{"crr", "coefficient of rolling resistance", calc.Crr, 0, inf, false, ""}
+	grP     = param{"gr", "road gradient (rise/run)", 0, -1, 1, false, ""}
+	mtP     = param{"mt", "total mass of the rider and bicycle in kg", 75, 0, inf, true, ""}
+	gP      = param{"g", "acceleration due to gravity in m/s²", calc.G, 0, inf, true, ""}
+	ecP     = param{"ec", "drivetrain efficiency", calc.Ec, 0, 1, true, ""}
+	fwP     = param{"fw", "incremental drag area of the spokes in m²", calc.Fw, 0, inf, false, ""}
+	vwP     = param{"vw", "wind speed in m/s", 0, 0, inf, false, ""}
+	dwP     = param{"dw", "direction the wind originates from in degrees", 0, -inf, inf, false, ""}
+	dbP     = param{"db", "direction the bicycle is travelling in degrees", 0, -inf, inf, false, ""}
+	pP      = param{"p", "net total power in watts", req, 0, inf, false, ""}
+	motionP = []param{rhoP, cdaP, crrP, vwP, dwP, dbP, grP, mtP, gP, ecP, fwP}
+)
+
+// ENDPOINTS are the endpoints served by 'serve'
+var ENDPOINTS = []endpoint{
+	{
+		path:    "/power",
+		summary: "Total power required broken down by component (Pcomp)",
+		params: []param{
+			rhoP, cdaP, crrP,
+			{"va", "air velocity in m/s (defaults to vg)", req, -inf, inf, false, "vg"},
+			{"vg", "ground velocity in m/s", req, 0, inf, false, ""},
+			grP, mtP,
+			{"r", "outside radius of the tire in m", calc.R700x23, 0, inf, true, ""},
+			{"vgi", "initial ground velocity in m/s (defaults to vg)", req, 0, inf, false, "vg"},
+			{"vgf", "final ground velocity in m/s (defaults to vg)", req, 0, inf, false, "vg"},
+			{"ti", "initial time in s", 0, 0, inf, false, ""},
+			{"tf", "final time in s", 1, 0, inf, false, ""},
+			gP, ecP, fwP,
+			{"i", "moment of inertia of the wheels in kg*m²", calc.I, 0, inf, false, ""},
+		},
+		results: []param{
+			{name: "p", description: "total power in watts"},
+			{name: "at", description: "power to overcome aerodynamic drag in watts"},
+			{name: "rr", description: "power to overcome rolling resistance in watts"},
+			{name: "wb", description: "power to overcome wheel bearing friction in watts"},
+			{name: "pe", description: "power to change potential energy in watts"},
+			{name: "ke", description: "power to change kinetic energy in watts"},
+		},
+		compute: func(v map[string]float64, _ map[string]bool) (map[string]float64, error) {
+			if v["tf"] <= v["ti"] {
+				return nil, &apiError{http.StatusUnprocessableEntity, "out_of_range", "tf", "tf must be greater than ti"}
+			}
+			c := calc.Pcomp(v["rho"], v["cda"], v["crr"], v["va"], v["vg"], v["gr"], v["mt"], v["r"],
+				v["vgi"], v["vgf"], v["ti"], v["tf"], v["g"], v["ec"], v["fw"], v["i"])
+			return map[string]float64{
+				"p": c.AT + c.RR + c.WB + c.PE + c.KE, "at": c.AT, "rr": c.RR, "wb": c.WB, "pe": c.PE, "ke": c.KE,
+			}, nil
+		},
+	},
+	{
+		path:    "/time",
+		summary: "Duration of a performance over a distance at constant power (T)",
+		params:  append([]param{pP, {"d", "distance in m", req, 0, inf, true, ""}}, motionP...),
+		results: []param{{name: "t", description: "duration in s"}},
+		compute: func(v map[string]float64, _ map[string]bool) (map[string]float64, error) {
+			return map[string]float64{"t": calc.T(v["p"], v["d"], v["rho"], v["cda"], v["crr"], v["vw"], v["dw"],
+				v["db"], v["gr"], v["mt"], v["g"], v["ec"], v["fw"])}, nil
+		},
+	},
+	{
+		path:    "/distance",
+		summary: "Distance of a performance over a duration at constant power (D)",
+		params:  append([]param{pP, {"t", "duration in s", req, 0, inf, true, ""}}, motionP...),
+		results: []param{{name: "d", description: "distance in m"}},
+		compute: func(v map[string]float64, _ map[string]bool) (map[string]float64, error) {
+			return map[string]float64{"d": calc.D(v["p"], v["t"], v["rho"], v["cda"], v["crr"], v["vw"], v["dw"],
+				v["db"], v["gr"], v["mt"], v["g"], v["ec"], v["fw"])}, nil
+		},
+	},
+	{
+		path:    "/speed",
+		summary: "Ground velocity at constant power (Vg)",
+		params:  append([]param{pP}, motionP...),
+		results: []param{
+			{name: "vg", description: "ground velocity in m/s"},
+			{name: "va", description: "air velocity in m/s"},
+		},
+		compute: func(v map[string]float64, _ map[string]bool) (map[string]float64, error) {
+			vg := calc.Vg(v["p"], v["rho"], v["cda"], v["crr"], v["vw"], v["dw"], v["db"], v["gr"], v["mt"],
+				v["g"], v["ec"], v["fw"])
+			return map[string]float64{"vg": vg, "va": calc.Va(vg, v["vw"], v["dw"], v["db"])}, nil
+		},
+	},
+	{
+		path:    "/density",
+		summary: "Air density at an altitude, in the standard atmosphere unless the weather is provided (Rho)",
+		params: []param{
+			{"h", "altitude in m", 0, -500, 11000, false, ""},
+			gP,
+			{"temp", "air temperature in Celsius", 15, -calc.K, inf, false, ""},
+			{"pressure", "barometric pressure in hPa", calc.P0 / 100.0, 0, inf, true, ""},
+			{"humidity", "relative humidity in %", 0, 0, 100, false, ""},
+		},
+		results: []param{{name: "rho", description: "air density in kg/m*3"}},
+		compute: func(v map[string]float64, set map[string]bool) (map[string]float64, error) {
+			if set["temp"] || set["pressure"] || set["humidity"] {
+				return map[string]float64{"rho": airDensity(v["h"], v["temp"], v["pressure"], v["humidity"],
+					set["temp"], set["pressure"])}, nil
+			}
+			return map[string]float64{"rho": calc.Rho(v["h"], v["g"])}, nil
+		},
+	},
+	{
+		path:    "/cda",
+		summary: "Typical CdA of a rider given their height and mass (CalculateDropsCdA and CalculateAeroCdA)",
+		params: []param{
+			{"h", "height of the rider in m", req, 0, 3, true, ""},
+			{"m", "mass of the rider in kg", req, 0, inf, true, ""},
+		},
+		results: []param{
+			{name: "drops", description: "CdA in the drops in m²"},
+			{name: "aero", description: "CdA in the aerobars in m²"},
+		},
+		compute: func(v map[string]float64, _ map[string]bool) (map[string]float64, error) {
+			return map[string]float64{
+				"drops": calc.CalculateDropsCdA(v["h"], v["m"]),
+				"aero":  calc.CalculateAeroCdA(v["h"], v["m"]),
+			}, nil
+		},
+	},
+}
+
+// serve starts an HTTP server exposing the ENDPOINTS and their OpenAPI
+// description at /openapi.json.
+func serve(args []string) {
+	var addr string
+
+	fs := subcommand("serve", "")
+	fs.StringVar(&addr, "addr", "localhost:8080", "address to listen on")
+	parse(fs, args)
+
+	s := &http.Server{
+		Addr:         addr,
+		Handler:      handler(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "listening on %s\n", addr)
+	exit(s.ListenAndServe())
+}
+
+// handler returns the handler for all of the ENDPOINTS. The handler holds no
+// mutable state and is safe for concurrent use.
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	for i := range ENDPOINTS {
+		mux.Handle(ENDPOINTS[i].path, &ENDPOINTS[i])
+	}
+	spec, err := json.MarshalIndent(openapi(), "", "  ")
+	if err != nil {
+		exit(err)
+	}
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusNotFound, &apiError{http.StatusNotFound, "not_found", "", fmt.Sprintf("no endpoint '%s'", r.URL.Path)})
+	})
+	return mux
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		respond(w, http.StatusMethodNotAllowed,
+			&apiError{http.StatusMethodNotAllowed, "method_not_allowed", "", fmt.Sprintf("%s requires POST", e.path)})
+		return
+	}
+
+	res, err := e.handle(w, r)
+	if err != nil {
+		ae, ok := err.(*apiError)
+		if !ok {
+			ae = &apiError{http.StatusInternalServerError, "internal", "", err.Error()}
+		}
+		respond(w, ae.status, ae)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+// handle decodes and validates the request and computes the results.
+func (e *endpoint) handle(w http.ResponseWriter, r *http.Request) (map[string]float64, error) {
+	body := make(map[string]interface{})
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	if err := dec.Decode(&body); err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid_json", "", fmt.Sprintf("invalid JSON object: %s", err)}
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return nil, &apiError{http.StatusBadRequest, "invalid_json", "", "invalid JSON object: unexpected data after the object"}
+	}
+
+	known := make(map[string]bool, len(e.params))
+	for _, p := range e.params {
+		known[p.name] = true
+	}
+	var fields []string
+	for name := range body {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+
+	v := make(map[string]float64, len(e.params))
+	set := make(map[string]bool, len(body))
+	for _, name := range fields {
+		if !known[name] {
+			return nil, &apiError{http.StatusBadRequest, "unknown_field", name, fmt.Sprintf("unknown field '%s'", name)}
+		}
+		x, ok := body[name].(float64)
+		if !ok {
+			return nil, &apiError{http.StatusBadRequest, "invalid_type", name, fmt.Sprintf("%s must be a number", name)}
+		}
+		v[name], set[name] = x, true
+	}
+
+	for _, p := range e.params {
+		x, ok := v[p.name]
+		if !ok {
+			if math.IsNaN(p.def) && p.ref == "" {
+				return nil, &apiError{http.StatusBadRequest, "missing_field", p.name, fmt.Sprintf("%s must be provided", p.name)}
+			}
+			v[p.name] = p.def
+			continue
+		}
+		if x < p.min || x > p.max || (p.positive && x <= p.min) {
+			return nil, &apiError{http.StatusUnprocessableEntity, "out_of_range", p.name,
+				fmt.Sprintf("%s must be %s but was %g", p.name, p.bounds(), x)}
+		}
+	}
+
+	for _, p := range e.params {
+		if p.ref != "" && !set[p.name] {
+			v[p.name] = v[p.ref]
+		}
+	}
+
+	res, err := e.compute(v, set)
+	if err != nil {
+		return nil, err
+	}
+	for _, x := range res {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, &apiError{http.StatusUnprocessableEntity, "no_solution", "", calc.ErrNoSolution.Error()}
+		}
+	}
+	return res, nil
+}
+
+// bounds describes the range of valid values of the param.
+func (p param) bounds() string {
+	lo := "["
+	if p.positive {
+		lo = "("
+	}
+	switch {
+	case math.IsInf(p.min, -1) && math.IsInf(p.max, 1):
+		return "finite"
+	case math.IsInf(p.max, 1) && p.positive:
+		return fmt.Sprintf("greater than %g", p.min)
+	case math.IsInf(p.max, 1):
+		return fmt.Sprintf("at least %g", p.min)
+	default:
+		return fmt.Sprintf("in %s%g, %g]", lo, p.min, p.max)
+	}
+}
+
+// respond writes v as the JSON response with the status code.
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// openapi returns the OpenAPI 3.0 description of the ENDPOINTS.
+func openapi() map[string]interface{} {
+	schema := func(params []param, request bool) map[string]interface{} {
+		props := make(map[string]interface{})
+		var required []string
+		for _, p := range params {
+			prop := map[string]interface{}{"type": "number", "description": p.description}
+			if request {
+				if math.IsNaN(p.def) && p.ref == "" {
+					required = append(required, p.name)
+				} else if !math.IsNaN(p.def) {
+					prop["default"] = p.def
+				}
+				if !math.IsInf(p.min, -1) {
+					prop["minimum"] = p.min
+					if p.positive {
+						prop["exclusiveMinimum"] = true
+					}
+				}
+				if !math.IsInf(p.max, 1) {
+					prop["maximum"] = p.max
+				}
+			} else {
+				required = append(required, p.name)
+			}
+			props[p.name] = prop
+		}
+		s := map[string]interface{}{"type": "object", "properties": props, "additionalProperties": false}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	content := func(s interface{}) map[string]interface{} {
+		return map[string]interface{}{"application/json": map[string]interface{}{"schema": s}}
+	}
+	errorRef := map[string]interface{}{"$ref": "#/components/schemas/Error"}
+
+	paths := make(map[string]interface{})
+	for _, e := range ENDPOINTS {
+		paths[e.path] = map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     e.summary,
+				"requestBody": map[string]interface{}{"required": true, "content": content(schema(e.params, true))},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{"description": "the results", "content": content(schema(e.results, false))},
+					"400": map[string]interface{}{"description": "the request is malformed", "content": content(errorRef)},
+					"422": map[string]interface{}{"description": "a field is out of range or there is no solution", "content": content(errorRef)},
+				},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "calc",
+			"description": "Mathematical formulas for modelling road cycling power",
+			"version":     "0.1.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"code", "message"},
+					"properties": map[string]interface{}{
+						"code": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"invalid_json", "unknown_field", "invalid_type", "missing_field",
+								"out_of_range", "no_solution", "method_not_allowed", "not_found", "internal"},
+						},
+						"field":   map[string]interface{}{"type": "string", "description": "the field which is invalid"},
+						"message": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/scheibo/calc"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		status             int
+		code, field        string
+	}{
+		{"POST", "/time", `{"p": 300, "d": 40000}`, http.StatusOK, "", ""},
+		{"POST", "/time", `{"p": 300, "d": 40000, "x": 1}`, http.StatusBadRequest, "unknown_field", "x"},
+		{"POST", "/time", `{"d": 40000}`, http.StatusBadRequest, "missing_field", "p"},
+		{"POST", "/time", `{"p": 300, "d": 40000, "gr": 2}`, http.StatusUnprocessableEntity, "out_of_range", "gr"},
+		{"POST", "/time", `{"p": 300, "d": 0}`, http.StatusUnprocessableEntity, "out_of_range", "d"},
+		{"POST", "/time", `{"p": "300", "d": 40000}`, http.StatusBadRequest, "invalid_type", "p"},
+		{"POST", "/time", `{"p": 0, "d": 40000}`, http.StatusUnprocessableEntity, "no_solution", ""},
+		{"POST", "/time", `{"p": 300, "d": 40000`, http.StatusBadRequest, "invalid_json", ""},
+		{"POST", "/time", `{"p": 300, "d": 40000} {"p": 200}`, http.StatusBadRequest, "invalid_json", ""},
+		{"POST", "/time", `{"p": 300, "d": 40000} x`, http.StatusBadRequest, "invalid_json", ""},
+		{"POST", "/power", `{"vg": 10, "ti": 1, "tf": 1}`, http.StatusUnprocessableEntity, "out_of_range", "tf"},
+		{"GET", "/time", "", http.StatusMethodNotAllowed, "method_not_allowed", ""},
+		{"POST", "/foo", `{}`, http.StatusNotFound, "not_found", ""},
+	}
+
+	h := handler()
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.status {
+			t.Errorf("%s %s %s: got: %d, want: %d", tt.method, tt.path, tt.body, w.Code, tt.status)
+			continue
+		}
+		if tt.code == "" {
+			continue
+		}
+		var e apiError
+		if err := json.NewDecoder(w.Body).Decode(&e); err != nil || e.Code != tt.code || e.Field != tt.field {
+			t.Errorf("%s %s %s: got: %+v (%v), want: code %s, field %s",
+				tt.method, tt.path, tt.body, e, err, tt.code, tt.field)
+		}
+	}
+}
+
+func TestHandlerParallel(t *testing.T) {
+	// n is the number of concurrent requests
+	const n = 50
+
+	s := httptest.NewServer(handler())
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(p float64) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"p": %g, "d": 10000, "gr": 0.02}`, p)
+			resp, err := http.Post(s.URL+"/time", "application/json", strings.NewReader(body))
+			if err != nil {
+				t.Errorf("POST /time %s: got: %v", body, err)
+				return
+			}
+			defer resp.Body.Close()
+
+			var res map[string]float64
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || resp.StatusCode != http.StatusOK {
+				t.Errorf("POST /time %s: got: %d (%v), want: %d", body, resp.StatusCode, err, http.StatusOK)
+				return
+			}
+			expected := calc.T(p, 10000, calc.Rho0, 0.325, calc.Crr, 0, 0, 0, 0.02, 75, calc.G, calc.Ec, calc.Fw)
+			if !calc.Eqf(res["t"], expected) {
+				t.Errorf("POST /time %s: got: %.3f, want: %.3f", body, res["t"], expected)
+			}
+		}(float64(100 + 10*i))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scheibo/calc"
+)
+
+// axis is an input varied by the -sweep flag, where the values are as they
+// were provided (e.g. gr is in %).
+type axis struct {
+	name   string
+	values []float64
+}
+
+// SweepFlag accumulates the inputs to vary, specified as 'name=start:stop:step'
+// or 'name=x,y,z'.
+type SweepFlag []axis
+
+func (sf *SweepFlag) String() string {
+	var s []string
+	for _, sw := range *sf {
+		s = append(s, sw.name)
+	}
+	return strings.Join(s, ",")
+}
+
+func (sf *SweepFlag) Set(v string) error {
+	kv := strings.SplitN(v, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("invalid sweep '%s'", v)
+	}
+	name := strings.ToLower(strings.TrimSpace(kv[0]))
+	if name != "p" && name != "t" && name != "mr" {
+		if _, err := calc.ParseVariable(name); err != nil {
+			return err
+		}
+	}
+
+	var values []float64
+	if r := strings.Split(kv[1], ":"); len(r) == 3 {
+		var xs [3]float64
+		for i, s := range r {
+			x, err := sweepValue(name, s)
+			if err != nil {
+				return err
+			}
+			xs[i] = x
+		}
+		if xs[2] <= 0 || xs[1] < xs[0] {
+			return fmt.Errorf("invalid range '%s'", kv[1])
+		}
+		// the values are rounded to the precision of the start and step so that
+		// they are printed as they would have been written
+		scale := math.Pow(10, float64(decimals(xs[0], xs[2])))
+		n := int(math.Floor((xs[1]-xs[0])/xs[2] + 1e-9))
+		for i := 0; i <= n; i++ {
+			values = append(values, math.Round((xs[0]+float64(i)*xs[2])*scale)/scale)
+		}
+	} else {
+		for _, s := range strings.Split(kv[1], ",") {
+			x, err := sweepValue(name, s)
+			if err != nil {
+				return err
+			}
+			values = append(values, x)
+		}
+	}
+
+	*sf = append(*sf, axis{name: name, values: values})
+	return nil
+}
+
+// decimals returns the greatest number of decimal places of the values xs.
+func decimals(xs ...float64) int {
+	n := 0
+	for _, x := range xs {
+		s := strconv.FormatFloat(x, 'f', -1, 64)
+		if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > n {
+			n = len(s) - i - 1
+		}
+	}
+	return n
+}
+
+// sweepValue parses the value s of the input name, where times may also be
+// durations.
+func sweepValue(name, s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if x, err := strconv.ParseFloat(s, 64); err == nil {
+		return x, nil
+	}
+	if name == "t" {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d.Seconds(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s '%s'", name, s)
+}
+
+// axes converts the sweeps to the units of the library given the bike mass mb.
+func (sf SweepFlag) axes(mb float64) []calc.Axis {
+	axes := make([]calc.Axis, len(sf))
+	for i, sw := range sf {
+		a := calc.Axis{Input: sw.name, Values: make([]float64, len(sw.values))}
+		for j, x := range sw.values {
+			switch sw.name {
+			case "gr":
+				x /= 100
+			case "mr":
+				x += mb
+			}
+			a.Values[j] = x
+		}
+		if sw.name == "mr" {
+			a.Input = "mt"
+		}
+		axes[i] = a
+	}
+	return axes
+}
+
+// sweep prints a table of the output of m riding the course for each
+// combination of the values of the sweeps. Unless specified, the output is the
+// power if a duration is provided or varied, the time if the course has a
+// distance, and otherwise the speed.
+func sweep(course calc.Course, m calc.Model, p, t float64, sf SweepFlag, output, format string, distance bool) {
+	timed, powered := t > 0, p != -1
+	for _, sw := range sf {
+		timed = timed || sw.name == "t"
+		powered = powered || sw.name == "p"
+	}
+
+	var out calc.Output
+	if output != "" {
+		o, ok := OUTPUTS[output]
+		if !ok {
+			exit(fmt.Errorf("invalid output '%s'", output))
+		}
+		out = o
+	} else if timed {
+		out = calc.OutputPower
+	} else if distance {
+		out = calc.OutputTime
+	} else {
+		out = calc.OutputSpeed
+	}
+	if out == calc.OutputPower && !timed {
+		exit(fmt.Errorf("t must be provided or swept to output power"))
+	}
+	if out != calc.OutputPower && !powered {
+		exit(fmt.Errorf("p must be provided or swept to output speed or time"))
+	}
+	if out == calc.OutputTime && !distance {
+		exit(fmt.Errorf("d or gpx must be provided to output time"))
+	}
+
+	cells, err := course.Sweep(m, p, t, out, sf.axes(m.Bike.Mass)...)
+	if err != nil {
+		exit(fmt.Errorf("unable to sweep: %s", err))
+	}
+	if err := table(os.Stdout, sf, out, cells, format); err != nil {
+		exit(err)
+	}
+}
+
+// OUTPUTS maps from the name of the output of a sweep to the output
+var OUTPUTS = map[string]calc.Output{
+	"speed": calc.OutputSpeed,
+	"time":  calc.OutputTime,
+	"power": calc.OutputPower,
+}
+
+// table prints the cells of a sweep as a table in the format (csv, markdown,
+// text or json). One or two sweeps are printed as a column or grid, more are
+// printed with one row per cell.
+func table(w io.Writer, sf SweepFlag, out calc.Output, cells []calc.Cell, format string) error {
+	if format == "json" {
+		return records(w, sf, out, cells)
+	}
+
+	heading := map[calc.Output]string{
+		calc.OutputSpeed: "speed (km/h)",
+		calc.OutputTime:  "time",
+		calc.OutputPower: "power (W)",
+	}[out]
+	name := func(sw axis) string {
+		if sw.name == "gr" {
+			return "gr (%)"
+		}
+		return sw.name
+	}
+	value := func(x float64) string {
+		switch {
+		case math.IsNaN(x):
+			return "-"
+		case out == calc.OutputSpeed:
+			return strconv.FormatFloat(x*3.6, 'f', 2, 64)
+		case out == calc.OutputTime && format == "csv":
+			return strconv.FormatFloat(x, 'f', 0, 64)
+		case out == calc.OutputTime:
+			return fmtDuration(time.Duration(x * float64(time.Second)))
+		default:
+			return strconv.FormatFloat(x, 'f', 2, 64)
+		}
+	}
+	input := func(x float64) string {
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+
+	var rows [][]string
+	if len(sf) == 2 {
+		header := []string{name(sf[0]) + " \\ " + name(sf[1])}
+		for _, x := range sf[1].values {
+			header = append(header, input(x))
+		}
+		rows = append(rows, header)
+		n := len(sf[1].values)
+		for i, x := range sf[0].values {
+			row := []string{input(x)}
+			for _, c := range cells[i*n : (i+1)*n] {
+				row = append(row, value(c.Value))
+			}
+			rows = append(rows, row)
+		}
+	} else {
+		var header []string
+		for _, sw := range sf {
+			header = append(header, name(sw))
+		}
+		rows = append(rows, append(header, heading))
+		for k, c := range cells {
+			// cells are in row-major order so the index of each input can be
+			// recovered from the index of the cell
+			row := make([]string, len(sf)+1)
+			for i, j := len(sf)-1, k; i >= 0; i-- {
+				n := len(sf[i].values)
+				row[i] = input(sf[i].values[j%n])
+				j /= n
+			}
+			row[len(sf)] = value(c.Value)
+			rows = append(rows, row)
+		}
+	}
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.WriteAll(rows)
+		return cw.Error()
+	case "markdown", "md":
+		for i, row := range rows {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+			if i == 0 {
+				sep := make([]string, len(row))
+				for j := range sep {
+					sep[j] = "---"
+				}
+				fmt.Fprintf(w, "| %s |\n", strings.Join(sep, " | "))
+			}
+		}
+		return nil
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		for _, row := range rows {
+			fmt.Fprintf(tw, "%s\t\n", strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("invalid format '%s'", format)
+	}
+}
+
+// records writes the cells of a sweep to w as a JSON array with an object for
+// each cell, where the output is in m/s, s or W (or null if there is no solution).
+func records(w io.Writer, sf SweepFlag, out calc.Output, cells []calc.Cell) error {
+	key := map[calc.Output]string{
+		calc.OutputSpeed: "vg",
+		calc.OutputTime:  "t",
+		calc.OutputPower: "p",
+	}[out]
+	rs := make([]map[string]interface{}, len(cells))
+	for k, c := range cells {
+		r := make(map[string]interface{}, len(sf)+1)
+		for i, j := len(sf)-1, k; i >= 0; i-- {
+			n := len(sf[i].values)
+			r[sf[i].name] = sf[i].values[j%n]
+			j /= n
+		}
+		if math.IsNaN(c.Value) {
+			r[key] = nil
+		} else {
+			r[key] = c.Value
+		}
+		rs[k] = r
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rs)
+}
@@ -0,0 +1,296 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Segment is a section of a Course over which the road has constant conditions.
+type Segment struct {
+	// Length is the distance travelled over the segment in metres.
+	Length float64
+	// Grade is the road gradient (rise/run) of the segment.
+	Grade float64
+	// Heading is the direction of travel of the bicycle in degrees.
+	Heading float64
+	// Elevation is the (mean) altitude of the segment in metres. If the Model
+	// being used to ride the course does not specify an air density (Rho is 0),
+	// the air density is calculated from the Elevation using Rho.
+	Elevation float64
+	// Crr is the coefficient of rolling resistance of the segment's surface. If
+	// 0, the Crr of the Model's Bike is used.
+	Crr float64
+}
+
+// Conditions returns the Conditions of the road over the segment.
+func (s Segment) Conditions() Conditions {
+	return Conditions{Grade: s.Grade, Heading: s.Heading}
+}
+
+// Course is a route made up of multiple segments.
+type Course []Segment
+
+// Distance returns the total length of the course in metres.
+func (c Course) Distance() float64 {
+	d := 0.0
+	for _, s := range c {
+		d += s.Length
+	}
+	return d
+}
+
+// Grade returns the average road gradient (net rise/run) of the course.
+func (c Course) Grade() float64 {
+	rise, d := 0.0, 0.0
+	for _, s := range c {
+		rise += s.Grade * s.Length
+		d += s.Length
+	}
+	if d == 0 {
+		return 0
+	}
+	return rise / d
+}
+
+// Elevation returns the average elevation of the course in metres, weighted by
+// the length of each segment.
+func (c Course) Elevation() float64 {
+	e, d := 0.0, 0.0
+	for _, s := range c {
+		e += s.Elevation * s.Length
+		d += s.Length
+	}
+	if d == 0 {
+		return 0
+	}
+	return e / d
+}
+
+// Split is the result of riding a single Segment of a Course.
+type Split struct {
+	Segment
+	// Time is the duration in seconds taken to ride the segment.
+	Time float64
+	// Speed is the ground velocity in m/s over the segment.
+	Speed float64
+	// Power is the net total power in watts produced over the segment.
+	Power float64
+	// Components is the breakdown of Power.
+	Components
+}
+
+// Result is the predicted performance over an entire Course.
+type Result struct {
+	// Time is the total duration in seconds.
+	Time float64
+	// Distance is the total distance in metres.
+	Distance float64
+	// Power is the average net total power in watts.
+	Power float64
+	// Splits are the results for each segment of the course.
+	Splits []Split
+}
+
+// Speed returns the average ground velocity in m/s of the performance.
+func (r Result) Speed() float64 {
+	return r.Distance / r.Time
+}
+
+// Components returns the average power required by each component over the
+// performance, weighted by the time spent on each segment.
+func (r Result) Components() Components {
+	var comp Components
+	if r.Time == 0 {
+		return comp
+	}
+	for _, s := range r.Splits {
+		f := s.Time / r.Time
+		comp.AT += s.AT * f
+		comp.RR += s.RR * f
+		comp.WB += s.WB * f
+		comp.PE += s.PE * f
+		comp.KE += s.KE * f
+	}
+	return comp
+}
+
+// Time predicts the performance over the course of Model m producing a constant
+// net total power p, solving for the ground velocity of each segment
+// independently based on its own grade and heading relative to the wind.
+// ErrNoSolution is returned if the course cannot be completed with p watts.
+func (c Course) Time(m Model, p float64) (Result, error) {
+	return c.pace(m, func(int) float64 { return p })
+}
+
+// Power predicts the constant net total power required for Model m to complete
+// the course in t seconds. ErrNoSolution is returned if no such power exists,
+// including when the course would be completed faster than t without any power.
+func (c Course) Power(m Model, t float64) (Result, error) {
+	// epsilon is the relative precision of the power required
+	const epsilon = 1e-9
+	// max is the maxmium power in watts which will be considered
+	const max = 1e6
+
+	if t <= 0 {
+		return Result{}, fmt.Errorf("t must be positive but was %f", t)
+	}
+	if len(c) == 0 {
+		return Result{}, fmt.Errorf("course has no segments")
+	}
+
+	// coasting must not already complete the course within the time...
+	pl := 0.0
+	rl, err := c.Time(m, pl)
+	if err == nil && rl.Time < t {
+		return Result{}, ErrNoSolution
+	} else if err != nil && err != ErrNoSolution {
+		return Result{}, err
+	}
+
+	// ... so find a power high enough to complete the course within the time...
+	ph := 100.0
+	rh, err := c.Time(m, ph)
+	for err != nil || rh.Time > t {
+		if err != nil && err != ErrNoSolution {
+			return Result{}, err
+		}
+		ph *= 2
+		if ph > max {
+			return Result{}, ErrNoSolution
+		}
+		rh, err = c.Time(m, ph)
+	}
+
+	// ... and then bisect towards the time required
+	for ph-pl > epsilon*ph {
+		pm := (pl + ph) / 2
+		rm, err := c.Time(m, pm)
+		if err != nil && err != ErrNoSolution {
+			return Result{}, err
+		}
+		if err != nil || rm.Time > t {
+			pl = pm
+		} else {
+			ph, rh = pm, rm
+		}
+	}
+
+	return rh, nil
+}
+
+// Best predicts the best possible performance over the course for Model m given
+// the maximum average power in watts mmp(t) the rider can sustain for a duration
+// of t seconds (e.g. CPFit.Power), i.e. the constant power which results in a
+// time the rider is just able to sustain it for.
+func (c Course) Best(m Model, mmp func(t float64) float64) (Result, error) {
+	// epsilon is the relative precision of the power
+	const epsilon = 1e-9
+	// max is the maximum power in watts which will be considered
+	const max = 1e6
+
+	if len(c) == 0 {
+		return Result{}, fmt.Errorf("course has no segments")
+	}
+
+	// the power is too high when it can't be sustained for as long as the
+	// course takes, which is monotonic as higher powers result in faster times
+	high := func(p float64) (bool, error) {
+		r, err := c.Time(m, p)
+		if err == ErrNoSolution {
+			return p >= mmp(math.Inf(1)), nil
+		} else if err != nil {
+			return false, err
+		}
+		return p >= mmp(r.Time), nil
+	}
+
+	ph := 100.0
+	for {
+		h, err := high(ph)
+		if err != nil {
+			return Result{}, err
+		}
+		if h {
+			break
+		}
+		ph *= 2
+		if ph > max {
+			return Result{}, ErrNoSolution
+		}
+	}
+
+	pl := 0.0
+	for ph-pl > epsilon*ph {
+		pm := (pl + ph) / 2
+		h, err := high(pm)
+		if err != nil {
+			return Result{}, err
+		}
+		if h {
+			ph = pm
+		} else {
+			pl = pm
+		}
+	}
+
+	return c.Time(m, ph)
+}
+
+// pace predicts the performance over the course of Model m producing a net
+// total power of power(i) over each segment i.
+func (c Course) pace(m Model, power func(i int) float64) (Result, error) {
+	r := Result{Splits: make([]Split, len(c))}
+	work := 0.0
+	for i, s := range c {
+		if s.Length < 0 {
+			return Result{}, fmt.Errorf("segment %d has a negative length %f", i, s.Length)
+		}
+
+		p := power(i)
+		ms := m.segment(s)
+		vg, err := ms.Speed(p, s.Conditions())
+		if err != nil {
+			return Result{}, err
+		}
+		if vg == 0 && s.Length > 0 {
+			return Result{}, ErrNoSolution
+		}
+
+		t := 0.0
+		if s.Length > 0 {
+			t = s.Length / vg
+		}
+		r.Splits[i] = Split{
+			Segment:    s,
+			Time:       t,
+			Speed:      vg,
+			Power:      p,
+			Components: ms.Components(vg, s.Conditions()),
+		}
+		r.Time += t
+		r.Distance += s.Length
+		work += p * t
+	}
+
+	if r.Time > 0 {
+		r.Power = work / r.Time
+	} else if len(c) > 0 {
+		r.Power = power(0)
+	}
+	if math.IsInf(r.Time, 0) || math.IsNaN(r.Time) {
+		return Result{}, ErrNoSolution
+	}
+	return r, nil
+}
+
+// segment returns the Model m adjusted for the surface and altitude of the
+// Segment s.
+func (m Model) segment(s Segment) Model {
+	if s.Crr > 0 {
+		m.Bike.Crr = s.Crr
+	}
+	if m.Environment.Rho == 0 {
+		m.Environment.Rho = Rho(s.Elevation, m.Environment.G)
+	}
+	return m
+}
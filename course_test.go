@@ -0,0 +1,125 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCourseTime(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	windy := testModel(67, 8, DropsCdA, Crr, Rho0, 5, 0)
+	tests := []struct {
+		m        Model
+		c        Course
+		p        float64
+		expected float64
+	}{
+		{m, Course{{Length: 4800, Grade: 0.08125}}, 389.9, 864.865},
+		{m, Course{{Length: 2400, Grade: 0.08125}, {Length: 2400, Grade: 0.08125}}, 389.9, 864.865},
+		{windy, Course{{Length: 5000, Heading: 0}},
+			250, T(250, 5000, Rho0, DropsCdA, Crr, 5, 0, 0, 0, 75, G, Ec, Fw)},
+		{windy, Course{{Length: 5000, Heading: 0}, {Length: 5000, Heading: 180}},
+			250, T(250, 5000, Rho0, DropsCdA, Crr, 5, 0, 0, 0, 75, G, Ec, Fw) +
+				T(250, 5000, Rho0, DropsCdA, Crr, 5, 0, 180, 0, 75, G, Ec, Fw)},
+		{m, Course{{Length: 5000, Crr: 0.008}},
+			250, T(250, 5000, Rho0, DropsCdA, 0.008, 0, 0, 0, 0, 75, G, Ec, Fw)},
+	}
+	for _, tt := range tests {
+		actual, err := tt.c.Time(tt.m, tt.p)
+		if err != nil || !Eqf(actual.Time, tt.expected) {
+			t.Errorf("%v.Time(%+v, %.3f): got: %.3f (%v), want: %.3f",
+				tt.c, tt.m, tt.p, actual.Time, err, tt.expected)
+		}
+	}
+}
+
+func TestCourseTimeElevation(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, 0, 0, 0)
+	c := Course{{Length: 5000, Elevation: 2000}}
+	expected := T(250, 5000, Rho(2000, G), DropsCdA, Crr, 0, 0, 0, 0, 75, G, Ec, Fw)
+	actual, err := c.Time(m, 250)
+	if err != nil || !Eqf(actual.Time, expected) {
+		t.Errorf("%v.Time(%+v, 250): got: %.3f (%v), want: %.3f", c, m, actual.Time, err, expected)
+	}
+}
+
+func TestCoursePower(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 3, 90)
+	tests := []struct {
+		c        Course
+		t        float64
+		expected float64
+	}{
+		{Course{{Length: 4800, Grade: 0.08125}}, 864.865,
+			Psimp(Rho0, DropsCdA, Crr, Va(4800/864.865, 3, 90, 0), 4800/864.865, 0.08125, 75, G, Ec, Fw)},
+		{Course{{Length: 3000, Grade: 0.02, Heading: 90}, {Length: 1000, Grade: -0.04, Heading: 270}}, 600, 181.211},
+	}
+	for _, tt := range tests {
+		actual, err := tt.c.Power(m, tt.t)
+		if err != nil || !Eqf(actual.Power, tt.expected) || !Eqf(actual.Time, tt.t) {
+			t.Errorf("%v.Power(%+v, %.3f): got: %.3f W @ %.3f s (%v), want: %.3f W",
+				tt.c, m, tt.t, actual.Power, actual.Time, err, tt.expected)
+		}
+	}
+}
+
+func TestCourseBest(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	f := CPFit{CriticalPower: CriticalPower{CP: 280, WPrime: 20000}, Model: TwoParameter, Pmax: math.Inf(1)}
+	c := Course{{Length: 5000, Grade: 0.07}, {Length: 500, Grade: 0.02}}
+
+	actual, err := c.Best(m, f.Power)
+	if err != nil || !Eqf(actual.Power, f.Power(actual.Time)) {
+		t.Fatalf("%v.Best(%+v, %+v): got: %.3f W @ %.3f s (%v), want: %.3f W",
+			c, m, f, actual.Power, actual.Time, err, f.Power(actual.Time))
+	}
+	expected, err := c.Time(m, actual.Power)
+	if err != nil || !Eqf(actual.Time, expected.Time) {
+		t.Errorf("%v.Best(%+v, %+v): got: %.3f s, want: %.3f s", c, m, f, actual.Time, expected.Time)
+	}
+}
+
+func TestCourseNoSolution(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Course{{Length: 1000, Grade: -0.05}, {Length: 1000, Grade: 0.05}}
+	if _, err := c.Time(m, 0); err != ErrNoSolution {
+		t.Errorf("%v.Time(%+v, 0): got: %v, want: %v", c, m, err, ErrNoSolution)
+	}
+}
+
+func TestCoursePowerNoSolution(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	tests := []struct {
+		c        Course
+		t        float64
+		expected error
+	}{
+		// the descent is faster than the time required without any power
+		{Course{{Length: 1000, Grade: -0.1}}, 600, ErrNoSolution},
+		{Course{{Length: 100000, Grade: 0.2}}, 60, ErrNoSolution},
+	}
+	for _, tt := range tests {
+		if actual, err := tt.c.Power(m, tt.t); err != tt.expected {
+			t.Errorf("%v.Power(%+v, %.3f): got: %.3f W @ %.3f s (%v), want: %v",
+				tt.c, m, tt.t, actual.Power, actual.Time, err, tt.expected)
+		}
+	}
+
+	c := Course{{Length: 1000}, {Length: -1}}
+	if _, err := c.Power(m, 600); err == nil || err == ErrNoSolution {
+		t.Errorf("%v.Power(%+v, 600): got: %v, want: negative length error", c, m, err)
+	}
+	if _, err := c.Best(m, func(float64) float64 { return 300 }); err == nil || err == ErrNoSolution {
+		t.Errorf("%v.Best(%+v, 300): got: %v, want: negative length error", c, m, err)
+	}
+}
+
+func TestCourseGradeElevation(t *testing.T) {
+	c := Course{{Length: 1000, Grade: 0.1, Elevation: 50}, {Length: 3000, Grade: -0.02, Elevation: 150}}
+	if actual := c.Grade(); !Eqf(actual, 0.01) {
+		t.Errorf("%v.Grade(): got: %.3f, want: %.3f", c, actual, 0.01)
+	}
+	if actual := c.Elevation(); !Eqf(actual, 125) {
+		t.Errorf("%v.Elevation(): got: %.3f, want: %.3f", c, actual, 125.0)
+	}
+}
@@ -0,0 +1,175 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// CPModel is a mathematical model of the power-duration relationship used to
+// estimate the critical power of a rider.
+type CPModel int
+
+const (
+	// TwoParameter is the hyperbolic model of Monod and Scherrer, P = CP + W′/t,
+	// which is only valid for efforts between roughly 2 and 20 minutes.
+	TwoParameter CPModel = iota
+	// ThreeParameter is the exponential model of Hopkins et al.,
+	// P = CP + (Pmax - CP) * exp(-t/τ), where W′ = (Pmax - CP) * τ.
+	ThreeParameter
+	// Morton is the 3-parameter hyperbolic model of Morton which bounds the
+	// power at short durations, P = CP + W′/(t + W′/(Pmax - CP)).
+	Morton
+)
+
+// CPFit is the result of fitting a CPModel to a rider's best efforts.
+type CPFit struct {
+	CriticalPower
+	// Model is the model which was fit.
+	Model CPModel
+	// Pmax is the maximum instantaneous power in watts, or +Inf for the
+	// TwoParameter model.
+	Pmax float64
+	// RMSE is the root mean squared error of the fit in watts.
+	RMSE float64
+	// R2 is the coefficient of determination of the fit.
+	R2 float64
+}
+
+// Power returns the maximum average power in watts the fitted model predicts
+// can be sustained for t seconds.
+func (f CPFit) Power(t float64) float64 {
+	switch f.Model {
+	case ThreeParameter:
+		a := f.Pmax - f.CP
+		return f.CP + a*math.Exp(-t*a/f.WPrime)
+	case Morton:
+		return f.CP + f.WPrime/(t+f.WPrime/(f.Pmax-f.CP))
+	default:
+		return f.CP + f.WPrime/t
+	}
+}
+
+// FitCriticalPower estimates the critical power and W′ of a rider from their
+// best efforts (e.g. as returned by MeanMaximalPower) using the CPModel model.
+// ErrNoSolution is returned if the efforts do not describe a valid model.
+func FitCriticalPower(efforts []Effort, model CPModel) (CPFit, error) {
+	min := 3
+	if model == TwoParameter {
+		min = 2
+	}
+	if len(efforts) < min {
+		return CPFit{}, fmt.Errorf("at least %d efforts are required but only %d were provided", min, len(efforts))
+	}
+	for _, e := range efforts {
+		if e.Duration <= 0 || e.Power <= 0 {
+			return CPFit{}, fmt.Errorf("invalid effort %+v", e)
+		}
+	}
+
+	var fit CPFit
+	var err error
+	switch model {
+	case TwoParameter:
+		fit, err = fitCP(efforts, model, 0)
+	case ThreeParameter, Morton:
+		// the model is linear in CP and W′ for a fixed time constant, so we
+		// only need to search for the time constant which minimizes the error
+		fit, err = searchCP(efforts, model)
+	default:
+		return CPFit{}, fmt.Errorf("invalid critical power model %d", model)
+	}
+	if err != nil {
+		return CPFit{}, err
+	}
+	if fit.CP <= 0 || fit.WPrime <= 0 || fit.Pmax <= fit.CP {
+		return CPFit{}, ErrNoSolution
+	}
+	return fit, nil
+}
+
+// fitCP fits the model to the efforts with the time constant k fixed.
+func fitCP(efforts []Effort, model CPModel, k float64) (CPFit, error) {
+	f := func(t float64) float64 {
+		switch model {
+		case ThreeParameter:
+			return math.Exp(-t / k)
+		case Morton:
+			return 1 / (t + k)
+		default:
+			return 1 / t
+		}
+	}
+
+	x := make([][]float64, len(efforts))
+	y := make([]float64, len(efforts))
+	for i, e := range efforts {
+		x[i] = []float64{1, f(e.Duration)}
+		y[i] = e.Power
+	}
+	beta, _, err := lstsq(x, y)
+	if err != nil {
+		return CPFit{}, err
+	}
+
+	fit := CPFit{CriticalPower: CriticalPower{CP: beta[0], WPrime: beta[1]}, Model: model, Pmax: math.Inf(1)}
+	switch model {
+	case ThreeParameter:
+		fit.Pmax, fit.WPrime = beta[0]+beta[1], beta[1]*k
+	case Morton:
+		fit.Pmax = beta[0] + beta[1]/k
+	}
+
+	mean := 0.0
+	for _, p := range y {
+		mean += p / float64(len(y))
+	}
+	sse, sst := 0.0, 0.0
+	for i, p := range y {
+		e := p - beta[0] - beta[1]*x[i][1]
+		sse += e * e
+		sst += (p - mean) * (p - mean)
+	}
+	fit.RMSE = math.Sqrt(sse / float64(len(y)))
+	fit.R2 = 1
+	if sst > 0 {
+		fit.R2 = 1 - sse/sst
+	}
+	return fit, nil
+}
+
+// searchCP fits the model to the efforts, searching for the time constant (in
+// log space) which minimizes the error of the fit.
+func searchCP(efforts []Effort, model CPModel) (CPFit, error) {
+	// lo and hi are the range of the base 10 logarithm of the time constant
+	const lo, hi = -1.0, 4.0
+	// n is the number of time constants initially evaluated
+	const n = 50
+
+	rmse := func(lk float64) float64 {
+		fit, err := fitCP(efforts, model, math.Pow(10, lk))
+		if err != nil {
+			return math.Inf(1)
+		}
+		return fit.RMSE
+	}
+
+	best, step := lo, (hi-lo)/n
+	for lk := lo; lk <= hi; lk += step {
+		if rmse(lk) < rmse(best) {
+			best = lk
+		}
+	}
+
+	// refine the best time constant with a golden section search
+	phi := (math.Sqrt(5) - 1) / 2
+	a, b := best-step, best+step
+	for b-a > 1e-9 {
+		c, d := b-phi*(b-a), a+phi*(b-a)
+		if rmse(c) < rmse(d) {
+			b = d
+		} else {
+			a = c
+		}
+	}
+	return fitCP(efforts, model, math.Pow(10, (a+b)/2))
+}
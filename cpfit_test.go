@@ -0,0 +1,70 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitCriticalPower(t *testing.T) {
+	tests := []struct {
+		expected  CPFit
+		durations []float64
+	}{
+		{CPFit{CriticalPower: CriticalPower{CP: 280, WPrime: 20000}, Model: TwoParameter, Pmax: math.Inf(1)},
+			[]float64{120, 180, 300, 600, 1200}},
+		{CPFit{CriticalPower: CriticalPower{CP: 280, WPrime: 20000}, Model: ThreeParameter, Pmax: 1000},
+			EffortDurations},
+		{CPFit{CriticalPower: CriticalPower{CP: 280, WPrime: 20000}, Model: Morton, Pmax: 1200},
+			EffortDurations},
+	}
+	for _, tt := range tests {
+		var efforts []Effort
+		for _, d := range tt.durations {
+			efforts = append(efforts, Effort{Duration: d, Power: tt.expected.Power(d)})
+		}
+		actual, err := FitCriticalPower(efforts, tt.expected.Model)
+		if err != nil || !Eqf(actual.CP, tt.expected.CP) || !Eqf(actual.WPrime, tt.expected.WPrime) ||
+			!(Eqf(actual.Pmax, tt.expected.Pmax) || math.IsInf(tt.expected.Pmax, 1) && math.IsInf(actual.Pmax, 1)) {
+			t.Errorf("FitCriticalPower(%v, %d): got: %+v (%v), want: %+v",
+				efforts, tt.expected.Model, actual, err, tt.expected)
+			continue
+		}
+		if actual.RMSE > 1e-3 || !Eqf(actual.R2, 1) {
+			t.Errorf("FitCriticalPower(%v, %d): got: RMSE %.3f R2 %.3f, want: RMSE 0 R2 1",
+				efforts, tt.expected.Model, actual.RMSE, actual.R2)
+		}
+	}
+}
+
+func TestFitCriticalPowerNoisy(t *testing.T) {
+	for _, model := range []CPModel{ThreeParameter, Morton} {
+		f := CPFit{CriticalPower: CriticalPower{CP: 300, WPrime: 18000}, Model: model, Pmax: 1100}
+		var efforts []Effort
+		for i, d := range EffortDurations {
+			// +/- 5 W of deterministic noise
+			efforts = append(efforts, Effort{Duration: d, Power: f.Power(d) + 5*math.Sin(float64(i))})
+		}
+		actual, err := FitCriticalPower(efforts, model)
+		if err != nil || !Eqf(actual.CP, 300, 1e-2) || !Eqf(actual.WPrime, 18000, 5e-2) || actual.RMSE > 5 {
+			t.Errorf("FitCriticalPower(%v, %d): got: %+v (%v), want: %+v", efforts, model, actual, err, f)
+		}
+	}
+}
+
+func TestFitCriticalPowerInvalid(t *testing.T) {
+	tests := []struct {
+		efforts []Effort
+		model   CPModel
+	}{
+		{[]Effort{{300, 400}}, TwoParameter},
+		{[]Effort{{300, 400}, {600, 350}}, Morton},
+		{[]Effort{{0, 400}, {600, 350}}, TwoParameter},
+		{[]Effort{{300, 400}, {600, 350}}, CPModel(-1)},
+		{[]Effort{{300, 350}, {600, 400}}, TwoParameter},
+	}
+	for _, tt := range tests {
+		if actual, err := FitCriticalPower(tt.efforts, tt.model); err == nil {
+			t.Errorf("FitCriticalPower(%v, %d): got: %+v, want: error", tt.efforts, tt.model, actual)
+		}
+	}
+}
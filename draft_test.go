@@ -0,0 +1,101 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestDraftFactor(t *testing.T) {
+	tests := []struct {
+		position, riders int
+		expected         float64
+	}{
+		{0, 1, 1},
+		{0, 4, 0.95},
+		{1, 2, 0.55},
+		{3, 4, 0.43},
+		{7, 8, 0.43},
+	}
+	for _, tt := range tests {
+		actual := DraftFactor(tt.position, tt.riders)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("DraftFactor(%d, %d): got: %.3f, want: %.3f", tt.position, tt.riders, actual, tt.expected)
+		}
+	}
+}
+
+func TestModelDraft(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	d := m.Draft(0.5)
+	if !Eqf(d.Rider.CdA, DropsCdA/2) || !Eqf(m.Rider.CdA, DropsCdA) {
+		t.Errorf("%+v.Draft(0.5): got: %.3f, want: %.3f", m, d.Rider.CdA, DropsCdA/2)
+	}
+
+	m.Rider.Curve = DropsCdACurve
+	d = m.Draft(0.5)
+	if actual := d.Rider.Curve.CdA(30); !Eqf(actual, 0.174) {
+		t.Errorf("%+v.Draft(0.5).Rider.Curve.CdA(30): got: %.3f, want: %.3f", m, actual, 0.174)
+	}
+
+	c := Conditions{Grade: 0.01}
+	if p, pd := m.Power(10, c), d.Power(10, c); pd >= p {
+		t.Errorf("%+v.Draft(0.5).Power(10, %+v): got: %.3f, want: < %.3f", m, c, pd, p)
+	}
+}
+
+func TestPacelinePower(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Conditions{Grade: 0.01}
+	vg := 12.0
+	pos := make([]float64, 4)
+	for j := range pos {
+		pos[j] = m.Draft(DraftFactor(j, 4)).Power(vg, c)
+	}
+
+	tests := []struct {
+		pulls    []float64
+		expected []float64
+	}{
+		{[]float64{30, 30, 30, 30}, []float64{
+			(pos[0] + pos[1] + pos[2] + pos[3]) / 4,
+			(pos[0] + pos[1] + pos[2] + pos[3]) / 4,
+			(pos[0] + pos[1] + pos[2] + pos[3]) / 4,
+			(pos[0] + pos[1] + pos[2] + pos[3]) / 4,
+		}},
+		{[]float64{60, 0, 30, 30}, []float64{
+			(pos[0]*60 + pos[2]*30 + pos[1]*30) / 120,
+			(pos[1]*60 + pos[3]*30 + pos[2]*30) / 120,
+			(pos[2]*60 + pos[0]*30 + pos[3]*30) / 120,
+			(pos[3]*60 + pos[1]*30 + pos[0]*30) / 120,
+		}},
+		{[]float64{0, 60, 0, 0}, []float64{pos[3], pos[0], pos[1], pos[2]}},
+	}
+	for _, tt := range tests {
+		pl := Paceline{Riders: []Model{m, m, m, m}, Pulls: tt.pulls}
+		actual, err := pl.Power(vg, c)
+		if err != nil {
+			t.Errorf("Paceline{%v}.Power(%.3f, %+v): got: %v", tt.pulls, vg, c, err)
+			continue
+		}
+		for i := range actual {
+			if !Eqf(actual[i], tt.expected[i]) {
+				t.Errorf("Paceline{%v}.Power(%.3f, %+v)[%d]: got: %.3f, want: %.3f",
+					tt.pulls, vg, c, i, actual[i], tt.expected[i])
+			}
+		}
+	}
+}
+
+func TestPacelinePowerInvalid(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	tests := []Paceline{
+		{},
+		{Riders: []Model{m, m}, Pulls: []float64{30}},
+		{Riders: []Model{m, m}, Pulls: []float64{30, -1}},
+		{Riders: []Model{m, m}, Pulls: []float64{0, 0}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.Power(10, Conditions{}); err == nil {
+			t.Errorf("Paceline{%v}.Power(10, {}): expected error", tt.Pulls)
+		}
+	}
+}
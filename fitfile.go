@@ -0,0 +1,363 @@
+package calc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// fitEpoch is the time FIT timestamps are relative to (1989-12-31T00:00:00Z).
+var fitEpoch = time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// FIT global message and field numbers of the 'record' message.
+const (
+	fitRecord = 20
+
+	fitTimestamp        = 253
+	fitLat              = 0
+	fitLon              = 1
+	fitAltitude         = 2
+	fitHeartRate        = 3
+	fitCadence          = 4
+	fitDistance         = 5
+	fitSpeed            = 6
+	fitPower            = 7
+	fitTemperature      = 13
+	fitEnhancedSpeed    = 73
+	fitEnhancedAltitude = 78
+)
+
+// Record is a single sample of data recorded during a ride. Fields which were
+// not recorded are NaN.
+type Record struct {
+	// Time is the time the sample was recorded.
+	Time time.Time
+	// Power is the power in watts.
+	Power float64
+	// Speed is the ground velocity in m/s.
+	Speed float64
+	// Distance is the cumulative distance travelled in metres.
+	Distance float64
+	// Altitude is the altitude in metres.
+	Altitude float64
+	// Lat is the latitude in degrees.
+	Lat float64
+	// Lon is the longitude in degrees.
+	Lon float64
+	// Temperature is the air temperature in Celsius.
+	Temperature float64
+	// Cadence is the pedalling cadence in rpm.
+	Cadence float64
+	// HeartRate is the heart rate in bpm.
+	HeartRate float64
+}
+
+// Rho calculates the air density of the Record from its altitude and
+// temperature, assuming the standard atmosphere if the temperature is unknown.
+func (r Record) Rho() float64 {
+	if math.IsNaN(r.Altitude) {
+		return Rho0
+	}
+	if math.IsNaN(r.Temperature) {
+		return Rho(r.Altitude, G)
+	}
+	return AirPressure(r.Altitude, r.Temperature) * M / (R * (r.Temperature + K))
+}
+
+type fitField struct {
+	num, size byte
+}
+
+type fitDefinition struct {
+	order  binary.ByteOrder
+	global uint16
+	fields []fitField
+	// dev is the total size of any developer fields, which are skipped
+	dev int
+}
+
+type fitReader struct {
+	r   *bufio.Reader
+	n   int
+	crc uint16
+}
+
+func (f *fitReader) Read(p []byte) (int, error) {
+	n, err := io.ReadFull(f.r, p)
+	f.n += n
+	f.crc = fitCRC(f.crc, p[:n])
+	return n, err
+}
+
+func (f *fitReader) byte() (byte, error) {
+	var b [1]byte
+	_, err := f.Read(b[:])
+	return b[0], err
+}
+
+// ParseFIT decodes the 'record' messages of the FIT activity file read from r.
+// Developer fields and unknown messages are skipped.
+func ParseFIT(r io.Reader) ([]Record, error) {
+	f := &fitReader{r: bufio.NewReader(r)}
+
+	hdr := make([]byte, 12)
+	if _, err := f.Read(hdr[:1]); err != nil {
+		return nil, fmt.Errorf("unable to read fit header: %s", err)
+	}
+	if hdr[0] != 12 && hdr[0] != 14 {
+		return nil, fmt.Errorf("invalid fit header size %d", hdr[0])
+	}
+	hdr = append(hdr[:1], make([]byte, hdr[0]-1)...)
+	if _, err := f.Read(hdr[1:]); err != nil {
+		return nil, fmt.Errorf("unable to read fit header: %s", err)
+	}
+	if string(hdr[8:12]) != ".FIT" {
+		return nil, fmt.Errorf("invalid fit file signature %q", hdr[8:12])
+	}
+	size := int(binary.LittleEndian.Uint32(hdr[4:8]))
+	if len(hdr) == 14 && binary.LittleEndian.Uint16(hdr[12:14]) != 0 && f.crc != 0 {
+		return nil, fmt.Errorf("invalid fit header crc")
+	}
+
+	start := f.n
+	defs := make(map[byte]*fitDefinition)
+	var records []Record
+	var last uint32
+	for f.n-start < size {
+		h, err := f.byte()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read fit record header: %s", err)
+		}
+
+		if h&0x80 != 0 {
+			// compressed timestamp header
+			local := (h >> 5) & 0x3
+			offset := uint32(h & 0x1F)
+			last += (offset - last&0x1F) & 0x1F
+			def, ok := defs[local]
+			if !ok {
+				return nil, fmt.Errorf("missing fit definition for local message %d", local)
+			}
+			rec, err := f.data(def, &last)
+			if err != nil {
+				return nil, err
+			}
+			if def.global == fitRecord {
+				records = append(records, rec)
+			}
+			continue
+		}
+
+		local := h & 0xF
+		if h&0x40 != 0 {
+			def, err := f.definition(h&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			defs[local] = def
+			continue
+		}
+
+		def, ok := defs[local]
+		if !ok {
+			return nil, fmt.Errorf("missing fit definition for local message %d", local)
+		}
+		rec, err := f.data(def, &last)
+		if err != nil {
+			return nil, err
+		}
+		if def.global == fitRecord {
+			records = append(records, rec)
+		}
+	}
+
+	crc := f.crc
+	var b [2]byte
+	if _, err := io.ReadFull(f.r, b[:]); err != nil {
+		return nil, fmt.Errorf("unable to read fit crc: %s", err)
+	}
+	if binary.LittleEndian.Uint16(b[:]) != crc {
+		return nil, fmt.Errorf("invalid fit crc")
+	}
+
+	return records, nil
+}
+
+// definition reads a definition message, including any developer fields if dev.
+func (f *fitReader) definition(dev bool) (*fitDefinition, error) {
+	b := make([]byte, 5)
+	if _, err := f.Read(b); err != nil {
+		return nil, fmt.Errorf("unable to read fit definition: %s", err)
+	}
+
+	def := &fitDefinition{order: binary.LittleEndian}
+	if b[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(b[2:4])
+
+	fields := make([]byte, 3*int(b[4]))
+	if _, err := f.Read(fields); err != nil {
+		return nil, fmt.Errorf("unable to read fit definition: %s", err)
+	}
+	for i := 0; i < len(fields); i += 3 {
+		def.fields = append(def.fields, fitField{num: fields[i], size: fields[i+1]})
+	}
+
+	if dev {
+		n, err := f.byte()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read fit definition: %s", err)
+		}
+		fields := make([]byte, 3*int(n))
+		if _, err := f.Read(fields); err != nil {
+			return nil, fmt.Errorf("unable to read fit definition: %s", err)
+		}
+		for i := 0; i < len(fields); i += 3 {
+			def.dev += int(fields[i+1])
+		}
+	}
+
+	return def, nil
+}
+
+// data reads a data message described by def, updating the last timestamp if
+// the message contains one.
+func (f *fitReader) data(def *fitDefinition, last *uint32) (Record, error) {
+	nan := math.NaN()
+	rec := Record{
+		Power: nan, Speed: nan, Distance: nan, Altitude: nan, Lat: nan, Lon: nan,
+		Temperature: nan, Cadence: nan, HeartRate: nan,
+	}
+
+	var enhancedSpeed, enhancedAltitude bool
+	for _, fd := range def.fields {
+		b := make([]byte, fd.size)
+		if _, err := f.Read(b); err != nil {
+			return rec, fmt.Errorf("unable to read fit data: %s", err)
+		}
+
+		if fd.num == fitTimestamp {
+			if v, ok := fitUint(def.order, b); ok {
+				*last = uint32(v)
+			}
+			continue
+		}
+		if def.global != fitRecord {
+			continue
+		}
+
+		switch fd.num {
+		case fitLat, fitLon:
+			if v, ok := fitSint(def.order, b); ok {
+				deg := float64(v) * 180 / math.Pow(2, 31)
+				if fd.num == fitLat {
+					rec.Lat = deg
+				} else {
+					rec.Lon = deg
+				}
+			}
+		case fitAltitude:
+			if v, ok := fitUint(def.order, b); ok && !enhancedAltitude {
+				rec.Altitude = float64(v)/5 - 500
+			}
+		case fitEnhancedAltitude:
+			if v, ok := fitUint(def.order, b); ok {
+				rec.Altitude, enhancedAltitude = float64(v)/5-500, true
+			}
+		case fitHeartRate:
+			if v, ok := fitUint(def.order, b); ok {
+				rec.HeartRate = float64(v)
+			}
+		case fitCadence:
+			if v, ok := fitUint(def.order, b); ok {
+				rec.Cadence = float64(v)
+			}
+		case fitDistance:
+			if v, ok := fitUint(def.order, b); ok {
+				rec.Distance = float64(v) / 100
+			}
+		case fitSpeed:
+			if v, ok := fitUint(def.order, b); ok && !enhancedSpeed {
+				rec.Speed = float64(v) / 1000
+			}
+		case fitEnhancedSpeed:
+			if v, ok := fitUint(def.order, b); ok {
+				rec.Speed, enhancedSpeed = float64(v)/1000, true
+			}
+		case fitPower:
+			if v, ok := fitUint(def.order, b); ok {
+				rec.Power = float64(v)
+			}
+		case fitTemperature:
+			if v, ok := fitSint(def.order, b); ok {
+				rec.Temperature = float64(v)
+			}
+		}
+	}
+
+	if def.dev > 0 {
+		if _, err := f.Read(make([]byte, def.dev)); err != nil {
+			return rec, fmt.Errorf("unable to read fit developer data: %s", err)
+		}
+	}
+
+	rec.Time = fitEpoch.Add(time.Duration(*last) * time.Second)
+	return rec, nil
+}
+
+// fitUint decodes the unsigned integer b, returning false if it is the invalid
+// value for its type.
+func fitUint(order binary.ByteOrder, b []byte) (uint64, bool) {
+	var v, invalid uint64
+	switch len(b) {
+	case 1:
+		v, invalid = uint64(b[0]), 0xFF
+	case 2:
+		v, invalid = uint64(order.Uint16(b)), 0xFFFF
+	case 4:
+		v, invalid = uint64(order.Uint32(b)), 0xFFFFFFFF
+	default:
+		return 0, false
+	}
+	return v, v != invalid
+}
+
+// fitSint decodes the signed integer b, returning false if it is the invalid
+// value for its type.
+func fitSint(order binary.ByteOrder, b []byte) (int64, bool) {
+	switch len(b) {
+	case 1:
+		return int64(int8(b[0])), b[0] != 0x7F
+	case 2:
+		v := order.Uint16(b)
+		return int64(int16(v)), v != 0x7FFF
+	case 4:
+		v := order.Uint32(b)
+		return int64(int32(v)), v != 0x7FFFFFFF
+	default:
+		return 0, false
+	}
+}
+
+// fitCRCTable is the nibble lookup table for the FIT CRC-16 algorithm.
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// fitCRC updates crc with the bytes of b.
+func fitCRC(crc uint16, b []byte) uint16 {
+	for _, x := range b {
+		tmp := fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[x&0xF]
+		tmp = fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[(x>>4)&0xF]
+	}
+	return crc
+}
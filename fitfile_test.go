@@ -0,0 +1,138 @@
+package calc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// testFIT builds a FIT file containing a file_id message, records with both
+// normal and compressed timestamp headers, developer fields and an unknown
+// message type.
+func testFIT() []byte {
+	var data bytes.Buffer
+	le := binary.LittleEndian
+
+	// definition of an unknown message 0 (file_id) with a single uint16 field
+	data.Write([]byte{0x40, 0, 0, 0, 0, 1, 1, 2, 0x84})
+	data.Write([]byte{0x00, 0x0F, 0x00})
+
+	// definition of local message 1 as a record with developer fields
+	data.Write([]byte{0x61, 0, 0, 20, 0, 6,
+		253, 4, 0x86, // timestamp
+		0, 4, 0x85, // position_lat
+		1, 4, 0x85, // position_long
+		7, 2, 0x84, // power
+		6, 2, 0x84, // speed
+		78, 4, 0x86, // enhanced_altitude
+		1, 0, 2, 0, // a single 2 byte developer field
+	})
+	record := func(ts uint32, lat, lon int32, power, speed uint16, alt uint32) {
+		b := make([]byte, 22)
+		le.PutUint32(b[0:], ts)
+		le.PutUint32(b[4:], uint32(lat))
+		le.PutUint32(b[8:], uint32(lon))
+		le.PutUint16(b[12:], power)
+		le.PutUint16(b[14:], speed)
+		le.PutUint32(b[16:], alt)
+		le.PutUint16(b[20:], 0xBEEF)
+		data.WriteByte(0x01)
+		data.Write(b)
+	}
+	record(1000000000, 1<<29, -(1 << 29), 250, 10000, 2600)
+	record(1000000001, 1<<29, -(1 << 29), 0xFFFF, 10500, 2605)
+
+	// definition of local message 2 as a big endian record without a timestamp
+	data.Write([]byte{0x42, 0, 1, 0, 20, 3,
+		7, 2, 0x84, // power
+		13, 1, 0x01, // temperature
+		4, 1, 0x02, // cadence
+	})
+	// compressed timestamp header with local message 2 and offset 1000000003 & 0x1F
+	data.Write([]byte{0x80 | 2<<5 | byte(1000000003&0x1F), 0x01, 0x2C, 0xEC, 90})
+
+	var hdr bytes.Buffer
+	hdr.Write([]byte{14, 0x20, 0x08, 0x08})
+	binary.Write(&hdr, le, uint32(data.Len()))
+	hdr.WriteString(".FIT")
+	binary.Write(&hdr, le, fitCRC(0, hdr.Bytes()))
+
+	file := append(hdr.Bytes(), data.Bytes()...)
+	return append(file, byte(fitCRC(0, file)), byte(fitCRC(0, file)>>8))
+}
+
+func TestParseFIT(t *testing.T) {
+	records, err := ParseFIT(bytes.NewReader(testFIT()))
+	if err != nil {
+		t.Fatalf("ParseFIT: got: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("ParseFIT: got: %d records, want: 3", len(records))
+	}
+
+	start := time.Date(2021, time.September, 8, 1, 46, 40, 0, time.UTC)
+	tests := []struct {
+		actual, expected float64
+	}{
+		{records[0].Power, 250},
+		{records[0].Speed, 10},
+		{records[0].Altitude, 20},
+		{records[0].Lat, 45},
+		{records[0].Lon, -45},
+		{records[1].Altitude, 21},
+		{records[2].Power, 300},
+		{records[2].Temperature, -20},
+		{records[2].Cadence, 90},
+	}
+	for i, tt := range tests {
+		if !Eqf(tt.actual, tt.expected) {
+			t.Errorf("ParseFIT: field %d got: %.3f, want: %.3f", i, tt.actual, tt.expected)
+		}
+	}
+	if !math.IsNaN(records[1].Power) || !math.IsNaN(records[0].Temperature) {
+		t.Errorf("ParseFIT: got: %.3f %.3f, want: NaN", records[1].Power, records[0].Temperature)
+	}
+	for i, d := range []time.Duration{0, time.Second, 3 * time.Second} {
+		if !records[i].Time.Equal(start.Add(d)) {
+			t.Errorf("ParseFIT: record %d got: %s, want: %s", i, records[i].Time, start.Add(d))
+		}
+	}
+}
+
+func TestParseFITInvalid(t *testing.T) {
+	valid := testFIT()
+	corrupt := append([]byte{}, valid...)
+	corrupt[20] ^= 0xFF
+	tests := [][]byte{
+		nil,
+		valid[:10],
+		valid[:len(valid)-4],
+		corrupt,
+	}
+	for _, tt := range tests {
+		if _, err := ParseFIT(bytes.NewReader(tt)); err == nil {
+			t.Errorf("ParseFIT(%x): expected error", tt)
+		}
+	}
+}
+
+func TestRecordRho(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		altitude, temperature, expected float64
+	}{
+		{nan, nan, Rho0},
+		{1000, nan, 1.111},
+		{0, 15, Rho0},
+		{0, 35, 1.146},
+	}
+	for _, tt := range tests {
+		actual := Record{Altitude: tt.altitude, Temperature: tt.temperature}.Rho()
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("Record{%.3f, %.3f}.Rho(): got: %.3f, want: %.3f",
+				tt.altitude, tt.temperature, actual, tt.expected)
+		}
+	}
+}
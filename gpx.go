@@ -0,0 +1,192 @@
+package calc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// EarthRadius is the mean radius of the Earth in metres.
+const EarthRadius = 6371008.8
+
+// Smoothing is the default distance in metres over which elevation data is
+// averaged when building a Course from GPS data.
+const Smoothing = 100.0
+
+// MinSegment is the minimum length in metres of a segment of a Course built
+// from GPS data. Closer points are merged together to avoid small errors in
+// elevation or position resulting in extreme grades.
+const MinSegment = 20.0
+
+// Waypoint is a GPS position with an elevation.
+type Waypoint struct {
+	// Lat is the latitude in degrees.
+	Lat float64
+	// Lon is the longitude in degrees.
+	Lon float64
+	// Ele is the elevation in metres.
+	Ele float64
+}
+
+// Haversine calculates the great-circle distance in metres between two
+// Waypoints a and b, ignoring any difference in elevation.
+func Haversine(a, b Waypoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dlat, dlon := lat2-lat1, (b.Lon-a.Lon)*math.Pi/180
+	h := math.Pow(math.Sin(dlat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dlon/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing calculates the initial bearing in degrees (clockwise from north) of
+// travel from Waypoint a towards Waypoint b.
+func Bearing(a, b Waypoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dlon := (b.Lon - a.Lon) * math.Pi / 180
+	y := math.Sin(dlon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dlon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+type gpxPoint struct {
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+	Ele float64 `xml:"ele"`
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+// ParseGPX returns the Waypoints of all of the tracks (or, if there are no
+// tracks, the routes) contained in the GPX data read from r.
+func ParseGPX(r io.Reader) ([]Waypoint, error) {
+	var f gpxFile
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+
+	var wps []Waypoint
+	add := func(pts []gpxPoint) {
+		for _, p := range pts {
+			wps = append(wps, Waypoint{Lat: p.Lat, Lon: p.Lon, Ele: p.Ele})
+		}
+	}
+	for _, t := range f.Tracks {
+		for _, s := range t.Segments {
+			add(s.Points)
+		}
+	}
+	if len(wps) == 0 {
+		for _, r := range f.Routes {
+			add(r.Points)
+		}
+	}
+
+	if len(wps) < 2 {
+		return nil, fmt.Errorf("gpx contains %d points but at least 2 are required", len(wps))
+	}
+	return wps, nil
+}
+
+// ReadGPX reads the GPX data from r and returns the corresponding Course, see
+// NewCourse.
+func ReadGPX(r io.Reader) (Course, error) {
+	wps, err := ParseGPX(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewCourse(wps, Smoothing)
+}
+
+// NewCourse returns the Course travelling through the Waypoints wps. As GPS
+// elevation data is noisy, spikes are first removed with a median filter and
+// the elevations are then averaged over a moving window of smoothing metres.
+// Waypoints closer than MinSegment metres together are merged.
+func NewCourse(wps []Waypoint, smoothing float64) (Course, error) {
+	if len(wps) < 2 {
+		return nil, fmt.Errorf("%d waypoints were provided but at least 2 are required", len(wps))
+	}
+
+	x := make([]float64, len(wps))
+	for i := 1; i < len(wps); i++ {
+		x[i] = x[i-1] + Haversine(wps[i-1], wps[i])
+	}
+	ele := smooth(x, median(wps), smoothing)
+
+	var c Course
+	for i, j := 0, 1; j < len(wps); j++ {
+		if x[j]-x[i] < MinSegment && j < len(wps)-1 {
+			continue
+		}
+		if l := x[j] - x[i]; l > 0 {
+			c = append(c, Segment{
+				Length:    l,
+				Grade:     (ele[j] - ele[i]) / l,
+				Heading:   Bearing(wps[i], wps[j]),
+				Elevation: (ele[i] + ele[j]) / 2,
+			})
+		}
+		i = j
+	}
+
+	if len(c) == 0 {
+		return nil, fmt.Errorf("waypoints do not cover any distance")
+	}
+	return c, nil
+}
+
+// median returns the elevations of wps after applying a median filter over a
+// window of 5 points to remove any spikes.
+func median(wps []Waypoint) []float64 {
+	// w is the number of points on either side of the point being filtered
+	const w = 2
+
+	ele := make([]float64, len(wps))
+	window := make([]float64, 0, 2*w+1)
+	for i := range wps {
+		window = window[:0]
+		for j := i - w; j <= i+w; j++ {
+			if j >= 0 && j < len(wps) {
+				window = append(window, wps[j].Ele)
+			}
+		}
+		sort.Float64s(window)
+		ele[i] = window[len(window)/2]
+	}
+	return ele
+}
+
+// smooth returns the average of the elevations ele over a window of w metres
+// centered around each of the cumulative distances x.
+func smooth(x, ele []float64, w float64) []float64 {
+	if w <= 0 {
+		return ele
+	}
+
+	sum := make([]float64, len(ele)+1)
+	for i, e := range ele {
+		sum[i+1] = sum[i] + e
+	}
+
+	smoothed := make([]float64, len(ele))
+	lo, hi := 0, 0
+	for i := range ele {
+		for x[lo] < x[i]-w/2 {
+			lo++
+		}
+		for hi < len(ele) && x[hi] <= x[i]+w/2 {
+			hi++
+		}
+		smoothed[i] = (sum[hi] - sum[lo]) / float64(hi-lo)
+	}
+	return smoothed
+}
@@ -0,0 +1,91 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>Climb</name>
+    <trkseg>
+      <trkpt lat="37.0000" lon="-122.0000"><ele>100</ele></trkpt>
+      <trkpt lat="37.0010" lon="-122.0000"><ele>105</ele></trkpt>
+      <trkpt lat="37.0020" lon="-122.0000"><ele>110</ele></trkpt>
+      <trkpt lat="37.0030" lon="-122.0000"><ele>180</ele></trkpt>
+      <trkpt lat="37.0040" lon="-122.0000"><ele>120</ele></trkpt>
+      <trkpt lat="37.0050" lon="-122.0000"><ele>125</ele></trkpt>
+      <trkpt lat="37.0060" lon="-122.0000"><ele>130</ele></trkpt>
+      <trkpt lat="37.0060" lon="-121.9990"><ele>130</ele></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		a, b     Waypoint
+		expected float64
+	}{
+		{Waypoint{Lat: 37, Lon: -122}, Waypoint{Lat: 37.001, Lon: -122}, 111.195},
+		{Waypoint{Lat: 51.5007, Lon: 0.1246}, Waypoint{Lat: 40.6892, Lon: 74.0445}, 5574840},
+	}
+	for _, tt := range tests {
+		actual := Haversine(tt.a, tt.b)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("Haversine(%v, %v): got: %.3f, want: %.3f", tt.a, tt.b, actual, tt.expected)
+		}
+	}
+}
+
+func TestBearing(t *testing.T) {
+	tests := []struct {
+		a, b     Waypoint
+		expected float64
+	}{
+		{Waypoint{Lat: 37, Lon: -122}, Waypoint{Lat: 37.001, Lon: -122}, 0},
+		{Waypoint{Lat: 37, Lon: -122}, Waypoint{Lat: 37, Lon: -121.999}, 90},
+		{Waypoint{Lat: 37, Lon: -122}, Waypoint{Lat: 36.999, Lon: -122}, 180},
+		{Waypoint{Lat: 37, Lon: -122}, Waypoint{Lat: 37, Lon: -122.001}, 270},
+	}
+	for _, tt := range tests {
+		actual := Bearing(tt.a, tt.b)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("Bearing(%v, %v): got: %.3f, want: %.3f", tt.a, tt.b, actual, tt.expected)
+		}
+	}
+}
+
+func TestReadGPX(t *testing.T) {
+	c, err := ReadGPX(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatalf("ReadGPX: got: %v", err)
+	}
+	if len(c) != 7 {
+		t.Errorf("ReadGPX: got: %d segments, want: 7", len(c))
+	}
+	if !Eqf(c.Distance(), 6*111.195+88.80) {
+		t.Errorf("ReadGPX: got: %.3f m, want: %.3f m", c.Distance(), 6*111.195+88.80)
+	}
+	for i, s := range c {
+		if s.Grade > 0.1 || s.Grade < -0.1 {
+			t.Errorf("ReadGPX: segment %d has grade %.3f", i, s.Grade)
+		}
+	}
+	if !Eqf(c[6].Heading, 90) {
+		t.Errorf("ReadGPX: got: heading %.3f, want: heading 90", c[6].Heading)
+	}
+}
+
+func TestParseGPXInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"<gpx></gpx>",
+		`<gpx><rte><rtept lat="1" lon="1"/></rte></gpx>`,
+	}
+	for _, tt := range tests {
+		if _, err := ParseGPX(strings.NewReader(tt)); err == nil {
+			t.Errorf("ParseGPX(%q): expected error", tt)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package calc
+
+import (
+	"math"
+)
+
+// lstsq solves the linear least squares problem x * beta = y, returning beta
+// and the (unscaled) covariance matrix (x^T * x)^-1.
+func lstsq(x [][]float64, y []float64) ([]float64, [][]float64, error) {
+	if len(x) == 0 || len(x) != len(y) {
+		return nil, nil, ErrNoSolution
+	}
+
+	n := len(x[0])
+	xtx := make([][]float64, n)
+	xty := make([]float64, n)
+	for j := range xtx {
+		xtx[j] = make([]float64, n)
+	}
+	for i, row := range x {
+		for j := 0; j < n; j++ {
+			xty[j] += row[j] * y[i]
+			for k := 0; k < n; k++ {
+				xtx[j][k] += row[j] * row[k]
+			}
+		}
+	}
+
+	cov, err := invert(xtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	beta := make([]float64, n)
+	for j := 0; j < n; j++ {
+		for k := 0; k < n; k++ {
+			beta[j] += cov[j][k] * xty[k]
+		}
+	}
+	return beta, cov, nil
+}
+
+// invert returns the inverse of the square matrix a using Gauss-Jordan
+// elimination with partial pivoting. ErrNoSolution is returned if a is singular.
+func invert(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = make([]float64, 2*n)
+		copy(m[i], a[i])
+		m[i][n+i] = 1
+	}
+
+	scale := 0.0
+	for i := range a {
+		for j := range a[i] {
+			scale = math.Max(scale, math.Abs(a[i][j]))
+		}
+	}
+
+	for c := 0; c < n; c++ {
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(m[r][c]) > math.Abs(m[p][c]) {
+				p = r
+			}
+		}
+		if math.Abs(m[p][c]) <= 1e-14*scale {
+			return nil, ErrNoSolution
+		}
+		m[c], m[p] = m[p], m[c]
+
+		pivot := m[c][c]
+		for j := range m[c] {
+			m[c][j] /= pivot
+		}
+		for r := 0; r < n; r++ {
+			if r == c || m[r][c] == 0 {
+				continue
+			}
+			f := m[r][c]
+			for j := range m[r] {
+				m[r][j] -= f * m[c][j]
+			}
+		}
+	}
+
+	inv := make([][]float64, n)
+	for i := range m {
+		inv[i] = m[i][n:]
+	}
+	return inv, nil
+}
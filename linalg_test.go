@@ -0,0 +1,23 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestLstsq(t *testing.T) {
+	// y = 1 + 2x fit exactly, and a singular system
+	x := [][]float64{{1, 0}, {1, 1}, {1, 2}, {1, 3}}
+	y := []float64{1, 3, 5, 7}
+	beta, cov, err := lstsq(x, y)
+	if err != nil || !Eqf(beta[0], 1) || !Eqf(beta[1], 2) {
+		t.Errorf("lstsq(%v, %v): got: %v (%v), want: [1 2]", x, y, beta, err)
+	}
+	if !Eqf(cov[1][1], 0.2) {
+		t.Errorf("lstsq(%v, %v): got: cov %v, want: cov[1][1] = 0.2", x, y, cov)
+	}
+
+	x = [][]float64{{1, 2}, {2, 4}}
+	if _, _, err := lstsq(x, []float64{1, 2}); err != ErrNoSolution {
+		t.Errorf("lstsq(%v): got: %v, want: %v", x, err, ErrNoSolution)
+	}
+}
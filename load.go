@@ -0,0 +1,94 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Load describes how physiologically demanding a performance is.
+type Load struct {
+	// Duration is the duration of the performance in seconds.
+	Duration float64
+	// Average is the average power in watts.
+	Average float64
+	// NP is the Normalized Power in watts: the fourth root of the mean of the
+	// fourth power of the 30 second rolling average power.
+	NP float64
+	// IF is the Intensity Factor, the ratio of NP to FTP.
+	IF float64
+	// TSS is the Training Stress Score, where 100 is equivalent to riding at
+	// FTP for an hour.
+	TSS float64
+	// VI is the Variability Index, the ratio of NP to the average power.
+	VI float64
+}
+
+// TrainingLoad calculates the Load of the power series which was recorded every
+// dt seconds for a rider with a functional threshold power of ftp watts.
+func TrainingLoad(power []float64, dt, ftp float64) (Load, error) {
+	if dt <= 0 {
+		return Load{}, fmt.Errorf("dt must be positive but was %f", dt)
+	}
+	if ftp <= 0 {
+		return Load{}, fmt.Errorf("ftp must be positive but was %f", ftp)
+	}
+	if len(power) == 0 {
+		return Load{}, fmt.Errorf("power series is empty")
+	}
+
+	l := Load{Duration: float64(len(power)) * dt, NP: NormalizedPower(power, dt)}
+	for _, p := range power {
+		l.Average += p / float64(len(power))
+	}
+	l.IF = l.NP / ftp
+	l.TSS = l.Duration * l.NP * l.IF / (ftp * 3600) * 100
+	if l.Average > 0 {
+		l.VI = l.NP / l.Average
+	}
+	return l, nil
+}
+
+// NormalizedPower calculates the Normalized Power in watts of the power series
+// which was recorded every dt seconds. Series shorter than 30 seconds are
+// averaged over their entire duration.
+func NormalizedPower(power []float64, dt float64) float64 {
+	// window is the duration in seconds of the rolling average
+	const window = 30
+
+	if len(power) == 0 {
+		return 0
+	}
+	n := int(math.Round(window / dt))
+	if n < 1 {
+		n = 1
+	}
+	if n > len(power) {
+		n = len(power)
+	}
+
+	sum, total := 0.0, 0.0
+	for i, p := range power {
+		sum += p
+		if i >= n {
+			sum -= power[i-n]
+		}
+		if i >= n-1 {
+			total += math.Pow(sum/float64(n), 4)
+		}
+	}
+	return math.Pow(total/float64(len(power)-n+1), 0.25)
+}
+
+// PowerSeries returns the power produced over the performance sampled every dt
+// seconds.
+func (r Result) PowerSeries(dt float64) []float64 {
+	var power []float64
+	t, start := dt/2, 0.0
+	for _, s := range r.Splits {
+		for ; t < start+s.Time; t += dt {
+			power = append(power, s.Power)
+		}
+		start += s.Time
+	}
+	return power
+}
@@ -0,0 +1,80 @@
+package calc
+
+import (
+	"testing"
+)
+
+func series(blocks ...[2]float64) []float64 {
+	var power []float64
+	for _, b := range blocks {
+		for i := 0; i < int(b[0]); i++ {
+			power = append(power, b[1])
+		}
+	}
+	return power
+}
+
+func TestNormalizedPower(t *testing.T) {
+	tests := []struct {
+		power    []float64
+		dt       float64
+		expected float64
+	}{
+		{series([2]float64{3600, 250}), 1, 250},
+		{series([2]float64{30, 400}, [2]float64{30, 0}), 1, 270.790},
+		{series([2]float64{15, 400}, [2]float64{15, 0}), 2, 273.989},
+		{series([2]float64{10, 300}, [2]float64{10, 100}), 1, 200},
+		{nil, 1, 0},
+	}
+	for _, tt := range tests {
+		actual := NormalizedPower(tt.power, tt.dt)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("NormalizedPower(%v, %.3f): got: %.3f, want: %.3f", tt.power, tt.dt, actual, tt.expected)
+		}
+	}
+}
+
+func TestTrainingLoad(t *testing.T) {
+	tests := []struct {
+		power    []float64
+		ftp      float64
+		expected Load
+	}{
+		{series([2]float64{3600, 250}), 250, Load{Duration: 3600, Average: 250, NP: 250, IF: 1, TSS: 100, VI: 1}},
+		{series([2]float64{1800, 200}), 250, Load{Duration: 1800, Average: 200, NP: 200, IF: 0.8, TSS: 32, VI: 1}},
+		{series([2]float64{30, 400}, [2]float64{30, 0}), 300,
+			Load{Duration: 60, Average: 200, NP: 270.790, IF: 0.903, TSS: 1.358, VI: 1.354}},
+	}
+	for _, tt := range tests {
+		actual, err := TrainingLoad(tt.power, 1, tt.ftp)
+		if err != nil || !Eqf(actual.Duration, tt.expected.Duration) || !Eqf(actual.Average, tt.expected.Average) ||
+			!Eqf(actual.NP, tt.expected.NP) || !Eqf(actual.IF, tt.expected.IF) ||
+			!Eqf(actual.TSS, tt.expected.TSS) || !Eqf(actual.VI, tt.expected.VI) {
+			t.Errorf("TrainingLoad(power, 1, %.3f): got: %+v (%v), want: %+v", tt.ftp, actual, err, tt.expected)
+		}
+	}
+
+	for _, ftp := range []float64{0, -1} {
+		if _, err := TrainingLoad([]float64{100}, 1, ftp); err == nil {
+			t.Errorf("TrainingLoad({100}, 1, %.3f): expected error", ftp)
+		}
+	}
+	if _, err := TrainingLoad(nil, 1, 250); err == nil {
+		t.Errorf("TrainingLoad(nil, 1, 250): expected error")
+	}
+}
+
+func TestResultPowerSeries(t *testing.T) {
+	r := Result{Splits: []Split{{Time: 2.5, Power: 100}, {Time: 1.5, Power: 200}}}
+	expected := []float64{100, 100, 200, 200}
+	actual := r.PowerSeries(1)
+	if len(actual) != len(expected) {
+		t.Fatalf("%+v.PowerSeries(1): got: %v, want: %v", r, actual, expected)
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Errorf("%+v.PowerSeries(1): got: %v, want: %v", r, actual, expected)
+			break
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package calc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Effort is the best average power produced for a particular duration.
+type Effort struct {
+	// Duration is the duration of the effort in seconds.
+	Duration float64
+	// Power is the average power in watts.
+	Power float64
+}
+
+// EffortDurations are the durations in seconds of the efforts typically used to
+// describe the power-duration relationship of a rider.
+var EffortDurations = []float64{1, 5, 15, 30, 60, 120, 180, 300, 600, 1200, 1800, 3600}
+
+// PowerCurve is the mean maximal power curve of a rider: their best efforts
+// sorted by increasing duration.
+type PowerCurve []Effort
+
+// MeanMaximalPower returns the PowerCurve with the Effort with the highest
+// average power for each of the durations (in seconds) from the power series
+// which was recorded every dt seconds. Durations which are longer than the
+// series are omitted. If durations is nil, every duration up to 2 minutes is
+// included, with longer durations spaced geometrically such that computing the
+// curve for n samples takes O(n log n) time.
+func MeanMaximalPower(power []float64, dt float64, durations []float64) PowerCurve {
+	if durations == nil {
+		durations = curveDurations(len(power), dt)
+	}
+
+	sum := make([]float64, len(power)+1)
+	for i, p := range power {
+		sum[i+1] = sum[i] + p
+	}
+
+	var curve PowerCurve
+	for _, d := range durations {
+		n := int(math.Round(d / dt))
+		if n < 1 || n > len(power) {
+			continue
+		}
+		best := math.Inf(-1)
+		for i := n; i < len(sum); i++ {
+			best = math.Max(best, sum[i]-sum[i-n])
+		}
+		curve = append(curve, Effort{Duration: float64(n) * dt, Power: best / float64(n)})
+	}
+	sort.SliceStable(curve, func(i, j int) bool { return curve[i].Duration < curve[j].Duration })
+	return curve
+}
+
+// curveDurations returns the durations in seconds of the default PowerCurve of
+// n samples recorded every dt seconds.
+func curveDurations(n int, dt float64) []float64 {
+	// dense is the duration in seconds up to which every duration is included
+	const dense = 120
+	// ratio is the ratio between successive durations after dense
+	const ratio = 1.02
+
+	var durations []float64
+	for i := 1; i <= n; {
+		durations = append(durations, float64(i)*dt)
+		if float64(i)*dt < dense {
+			i++
+		} else {
+			i = int(math.Max(float64(i+1), math.Round(float64(i)*ratio)))
+		}
+	}
+	if n > 0 && durations[len(durations)-1] != float64(n)*dt {
+		durations = append(durations, float64(n)*dt)
+	}
+	return durations
+}
+
+// Power returns the best average power in watts for a duration of t seconds,
+// linearly interpolating between the durations of the curve. 0 is returned if
+// t is longer than the longest duration of the curve.
+func (c PowerCurve) Power(t float64) float64 {
+	i := sort.Search(len(c), func(i int) bool { return c[i].Duration >= t })
+	if i == len(c) {
+		return 0
+	}
+	if i == 0 || c[i].Duration == t {
+		return c[i].Power
+	}
+	lo, hi := c[i-1], c[i]
+	return lo.Power + (hi.Power-lo.Power)*(t-lo.Duration)/(hi.Duration-lo.Duration)
+}
+
+// Efforts returns the Effort of the curve at each of the durations, omitting
+// those longer than the curve.
+func (c PowerCurve) Efforts(durations []float64) []Effort {
+	var efforts []Effort
+	for _, d := range durations {
+		if len(c) == 0 || d > c[len(c)-1].Duration {
+			continue
+		}
+		efforts = append(efforts, Effort{Duration: d, Power: c.Power(d)})
+	}
+	return efforts
+}
+
+// FTPMethod is a method of estimating functional threshold power.
+type FTPMethod int
+
+const (
+	// TwentyMinute estimates FTP as 95% of the best 20 minute power.
+	TwentyMinute FTPMethod = iota
+	// CurveFit estimates FTP as the 60 minute power predicted by the Morton
+	// critical power model fit to the curve.
+	CurveFit
+)
+
+// FTP estimates the functional threshold power in watts of the rider with the
+// power curve c using the FTPMethod method.
+func (c PowerCurve) FTP(method FTPMethod) (float64, error) {
+	// hour is the duration in seconds FTP is defined by
+	const hour = 3600
+	// twenty is the duration in seconds of the test of the TwentyMinute method
+	const twenty = 1200
+
+	switch method {
+	case TwentyMinute:
+		p := c.Power(twenty)
+		if p == 0 {
+			return 0, fmt.Errorf("power curve is shorter than 20 minutes")
+		}
+		return 0.95 * p, nil
+	case CurveFit:
+		fit, err := FitCriticalPower(c.Efforts(EffortDurations), Morton)
+		if err != nil {
+			return 0, err
+		}
+		return fit.Power(hour), nil
+	default:
+		return 0, fmt.Errorf("invalid ftp method %d", method)
+	}
+}
+
+// ResamplePower resamples the power of the Records rs to a series recorded every
+// second. Missing power values and gaps in the recording are treated as 0 W.
+func ResamplePower(rs []Record) []float64 {
+	if len(rs) == 0 {
+		return nil
+	}
+
+	start := rs[0].Time
+	var power []float64
+	for _, r := range rs {
+		i := int(math.Round(r.Time.Sub(start).Seconds()))
+		if i < len(power) {
+			continue
+		}
+		for len(power) < i {
+			power = append(power, 0)
+		}
+		p := r.Power
+		if math.IsNaN(p) {
+			p = 0
+		}
+		power = append(power, p)
+	}
+	return power
+}
+
+// ReadPower parses a power series recorded every second from CSV data read from
+// r. If the first row is a header the power is read from the column named
+// 'power', 'watts' or 'p', otherwise the first column is used. Missing values are
+// treated as 0 W.
+func ReadPower(r io.Reader) ([]float64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	col := 0
+	if _, err := strconv.ParseFloat(strings.TrimSpace(rows[0][0]), 64); err != nil {
+		col = -1
+		for i, name := range rows[0] {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "power", "watts", "p":
+				col = i
+			}
+		}
+		if col < 0 {
+			return nil, fmt.Errorf("missing 'power' column")
+		}
+		rows = rows[1:]
+	}
+
+	power := make([]float64, len(rows))
+	for i, row := range rows {
+		if col >= len(row) || strings.TrimSpace(row[col]) == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(row[col]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid power '%s' on row %d", row[col], i+1)
+		}
+		power[i] = p
+	}
+	return power, nil
+}
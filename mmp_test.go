@@ -0,0 +1,137 @@
+package calc
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeanMaximalPower(t *testing.T) {
+	power := []float64{100, 200, 300, 400, 100, 0}
+	expected := []Effort{{1, 400}, {2, 350}, {4, 250}, {6, 1100.0 / 6}}
+	actual := MeanMaximalPower(power, 1, []float64{1, 2, 4, 6, 10})
+	if len(actual) != len(expected) {
+		t.Fatalf("MeanMaximalPower(%v): got: %v, want: %v", power, actual, expected)
+	}
+	for i := range actual {
+		if actual[i].Duration != expected[i].Duration || !Eqf(actual[i].Power, expected[i].Power) {
+			t.Errorf("MeanMaximalPower(%v): got: %v, want: %v", power, actual, expected)
+		}
+	}
+}
+
+func TestResamplePower(t *testing.T) {
+	start := time.Date(2021, time.September, 8, 0, 0, 0, 0, time.UTC)
+	rs := []Record{
+		{Time: start, Power: 100},
+		{Time: start.Add(time.Second), Power: math.NaN()},
+		{Time: start.Add(time.Second), Power: 500},
+		{Time: start.Add(4 * time.Second), Power: 200},
+	}
+	expected := []float64{100, 0, 0, 0, 200}
+	actual := ResamplePower(rs)
+	if len(actual) != len(expected) {
+		t.Fatalf("ResamplePower: got: %v, want: %v", actual, expected)
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Errorf("ResamplePower: got: %v, want: %v", actual, expected)
+			break
+		}
+	}
+}
+
+func TestReadPower(t *testing.T) {
+	tests := []struct {
+		csv      string
+		expected []float64
+	}{
+		{"secs,watts,hr\n0,100,120\n1,,121\n2,300,122\n", []float64{100, 0, 300}},
+		{"150\n250\n", []float64{150, 250}},
+	}
+	for _, tt := range tests {
+		actual, err := ReadPower(strings.NewReader(tt.csv))
+		if err != nil || len(actual) != len(tt.expected) {
+			t.Errorf("ReadPower(%q): got: %v (%v), want: %v", tt.csv, actual, err, tt.expected)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != tt.expected[i] {
+				t.Errorf("ReadPower(%q): got: %v, want: %v", tt.csv, actual, tt.expected)
+				break
+			}
+		}
+	}
+
+	for _, csv := range []string{"time,hr\n0,120\n", "power\nabc\n"} {
+		if _, err := ReadPower(strings.NewReader(csv)); err == nil {
+			t.Errorf("ReadPower(%q): expected error", csv)
+		}
+	}
+}
+
+func TestMeanMaximalPowerCurve(t *testing.T) {
+	power := make([]float64, 3000)
+	for i := range power {
+		power[i] = 200 + 100*math.Sin(float64(i)/50)
+	}
+	c := MeanMaximalPower(power, 1, nil)
+	if c[0].Duration != 1 || c[119].Duration != 120 || c[len(c)-1].Duration != 3000 {
+		t.Errorf("MeanMaximalPower(nil): got: durations %.0f, %.0f, %.0f, want: 1, 120, 3000",
+			c[0].Duration, c[119].Duration, c[len(c)-1].Duration)
+	}
+	for i, e := range c {
+		if i > 0 && e.Duration <= c[i-1].Duration {
+			t.Errorf("MeanMaximalPower(nil): got: %v after %v, want: longer", e, c[i-1])
+		}
+		expected := MeanMaximalPower(power, 1, []float64{e.Duration})[0].Power
+		if !Eqf(e.Power, expected) {
+			t.Errorf("MeanMaximalPower(nil): got: %.3f W for %.0f s, want: %.3f W", e.Power, e.Duration, expected)
+		}
+	}
+}
+
+func TestPowerCurvePower(t *testing.T) {
+	c := PowerCurve{{1, 1000}, {60, 400}, {300, 340}}
+	tests := []struct {
+		t, expected float64
+	}{
+		{0.5, 1000},
+		{60, 400},
+		{180, 370},
+		{600, 0},
+	}
+	for _, tt := range tests {
+		actual := c.Power(tt.t)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("%v.Power(%.3f): got: %.3f, want: %.3f", c, tt.t, actual, tt.expected)
+		}
+	}
+}
+
+func TestPowerCurveFTP(t *testing.T) {
+	f := CPFit{CriticalPower: CriticalPower{CP: 280, WPrime: 20000}, Model: Morton, Pmax: 1200}
+	var c PowerCurve
+	for _, d := range EffortDurations {
+		c = append(c, Effort{Duration: d, Power: f.Power(d)})
+	}
+
+	tests := []struct {
+		method   FTPMethod
+		expected float64
+	}{
+		{TwentyMinute, 0.95 * f.Power(1200)},
+		{CurveFit, f.Power(3600)},
+	}
+	for _, tt := range tests {
+		actual, err := c.FTP(tt.method)
+		if err != nil || !Eqf(actual, tt.expected) {
+			t.Errorf("%v.FTP(%d): got: %.3f (%v), want: %.3f", c, tt.method, actual, err, tt.expected)
+		}
+	}
+
+	if _, err := c[:5].FTP(TwentyMinute); err == nil {
+		t.Errorf("%v.FTP(%d): expected error", c[:5], TwentyMinute)
+	}
+}
@@ -0,0 +1,260 @@
+package calc
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoSolution is returned when a solver is unable to find a solution.
+var ErrNoSolution = errors.New("no solution exists")
+
+// Rider describes the physical characteristics of the cyclist.
+type Rider struct {
+	// Mass is the mass of the rider in kg.
+	Mass float64
+	// CdA is the coefficient of drag multiplied by the frontal area of the
+	// rider in squared metres.
+	CdA float64
+	// Curve optionally describes how the CdA varies with the yaw angle. If
+	// present, it is used instead of the constant CdA.
+	Curve CdACurve
+}
+
+// Bike describes the equipment being ridden.
+type Bike struct {
+	// Mass is the mass of the bicycle in kg.
+	Mass float64
+	// Crr is the coefficient of rolling resistance of the tires.
+	Crr float64
+	// TireRadius is the outside radius of the tire in metres.
+	TireRadius float64
+	// WheelInertia is the moment of inertia of the two wheels in kg*m^2.
+	WheelInertia float64
+	// DrivetrainEfficiency is the drive chain efficiency factor (see Ec).
+	DrivetrainEfficiency float64
+	// Fw is the incremental drag area of the spokes in squared metres.
+	Fw float64
+}
+
+// Wind describes the absolute wind velocity.
+type Wind struct {
+	// Speed is the wind speed in m/s.
+	Speed float64
+	// Direction is the direction the wind originates from in degrees.
+	Direction float64
+}
+
+// Environment describes the conditions the performance takes place in.
+type Environment struct {
+	// Rho is the air density in kg/m^3.
+	Rho float64
+	// G is the acceleration of gravity in m/s^2.
+	G float64
+	// Wind is the absolute wind velocity.
+	Wind Wind
+}
+
+// Conditions describes the road at a particular point of a performance.
+type Conditions struct {
+	// Grade is the road gradient (rise/run).
+	Grade float64
+	// Heading is the direction of travel of the bicycle in degrees.
+	Heading float64
+}
+
+// Model combines a Rider, Bike and Environment to allow for computing the
+// power and velocity of a performance without needing to thread every
+// parameter through the positional functions of this package.
+type Model struct {
+	Rider       Rider
+	Bike        Bike
+	Environment Environment
+}
+
+// Mass returns the total mass of the rider and the bike in kg.
+func (m Model) Mass() float64 {
+	return m.Rider.Mass + m.Bike.Mass
+}
+
+// Va returns the air velocity of the bicycle given the ground velocity vg and
+// the Conditions c.
+func (m Model) Va(vg float64, c Conditions) float64 {
+	return Va(vg, m.Environment.Wind.Speed, m.Environment.Wind.Direction, c.Heading)
+}
+
+// Yaw returns the yaw angle in degrees of the bicycle relative to the wind given
+// the ground velocity vg and the Conditions c.
+func (m Model) Yaw(vg float64, c Conditions) float64 {
+	w := m.Environment.Wind
+	return Yaw(m.Va(vg, c), w.Speed, w.Direction, c.Heading)
+}
+
+// CdA returns the CdA of the rider given the ground velocity vg and the
+// Conditions c, taking into account the yaw angle if the Rider has a Curve.
+func (m Model) CdA(vg float64, c Conditions) float64 {
+	if m.Rider.Curve == nil {
+		return m.Rider.CdA
+	}
+	yaw := 0.0
+	if m.Environment.Wind.Speed != 0 {
+		yaw = m.Yaw(vg, c)
+	}
+	// the yaw angle is undefined when there is no air velocity
+	if math.IsNaN(yaw) {
+		yaw = 0
+	}
+	return m.Rider.Curve.CdA(yaw)
+}
+
+// Components calculates the power required to maintain a steady ground
+// velocity vg under Conditions c, broken down by component. KE is always 0.
+func (m Model) Components(vg float64, c Conditions) Components {
+	return m.steady(m.CdA(vg, c), m.Va(vg, c), vg, c.Grade)
+}
+
+// Power calculates the total power required to maintain a steady ground
+// velocity vg under Conditions c. This is equivalent to Psimp.
+func (m Model) Power(vg float64, c Conditions) float64 {
+	comp := m.Components(vg, c)
+	return comp.AT + comp.RR + comp.WB + comp.PE
+}
+
+// Speed calculates the equilibrium ground velocity in m/s which can be
+// maintained with a net total power of p under Conditions c. The required power
+// is a cubic polynomial in the ground velocity, so the velocity is found by
+// solving the cubic directly rather than searching. When p is 0 the result is
+// the terminal velocity on a descent (or 0 if the bicycle would not roll).
+// ErrNoSolution is returned if no such velocity exists, or if the CdA depends on
+// the yaw angle and no velocity consistent with it can be found.
+func (m Model) Speed(p float64, c Conditions) (float64, error) {
+	return m.speed(c)(p)
+}
+
+// speed returns a function which calculates the equilibrium ground velocity
+// for a net total power p under Conditions c (see Speed). The coefficients of
+// the cubic polynomial are computed once, so the function is suitable for
+// solving for many different powers under the same Conditions.
+func (m Model) speed(c Conditions) func(p float64) (float64, error) {
+	// eps is the smallest velocity we consider to be distinct from stationary
+	const eps = 1e-9
+	// max is the maximum number of iterations when the CdA depends on yaw
+	const max = 100
+	// tolerance is the relative error in the power of a velocity which did
+	// not converge within max iterations
+	const tolerance = 1e-6
+
+	// If the CdA depends on the yaw angle the power required is no longer a
+	// cubic polynomial, so we iterate: solving for the velocity with the CdA
+	// fixed at the yaw angle of the previous velocity until it converges.
+	if curve := m.Rider.Curve; curve != nil {
+		return func(p float64) (float64, error) {
+			mc := m
+			mc.Rider.Curve = nil
+			mc.Rider.CdA = curve.CdA(0)
+			vg, err := mc.Speed(p, c)
+			for j := 0; err == nil && j < max; j++ {
+				mc.Rider.CdA = m.CdA(vg, c)
+				prev := vg
+				vg, err = mc.Speed(p, c)
+				if math.Abs(vg-prev) <= eps {
+					return vg, err
+				}
+			}
+			if err != nil {
+				return 0, err
+			}
+			// The iteration may oscillate if the CdA changes sharply with the
+			// yaw angle, in which case the velocity is only accepted if it
+			// still requires the power provided.
+			if math.Abs(m.Power(vg, c)-p) > tolerance*math.Max(1, math.Abs(p)) {
+				return 0, ErrNoSolution
+			}
+			return vg, nil
+		}
+	}
+
+	// Power(vg) = c3*vg^3 + c2*vg^2 + c1*vg + c0, so we can recover the
+	// coefficients exactly from the forward differences at 0, 1, 2 and 3 m/s.
+	p0, p1, p2, p3 := m.Power(0, c), m.Power(1, c), m.Power(2, c), m.Power(3, c)
+	c3 := (p3 - 3*p2 + 3*p1 - p0) / 6
+	c2 := (p2-2*p1+p0)/2 - 3*c3
+	c1 := p1 - p0 - c2 - c3
+
+	return func(p float64) (float64, error) {
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			return 0, ErrNoSolution
+		}
+
+		// Without any power a bicycle which isn't on a descent stays where it
+		// is.
+		if p == 0 && c1 >= 0 {
+			return 0, nil
+		}
+
+		for _, vg := range cubic(c3, c2, c1, p0-p) {
+			if vg <= eps {
+				continue
+			}
+			// Starting from rest with positive power the bicycle accelerates
+			// until it reaches the first root, otherwise the only stable
+			// equilibrium is where the required power is increasing with the
+			// velocity.
+			if p > 0 || (3*c3*vg+2*c2)*vg+c1 > 0 {
+				return vg, nil
+			}
+		}
+
+		return 0, ErrNoSolution
+	}
+}
+
+// pcomp calculates the components of power given an explicit air velocity va,
+// ground velocity vg, road gradient gr and the initial and final ground
+// velocities vgi and vgf at times ti and tf.
+func (m Model) pcomp(va, vg, gr, vgi, vgf, ti, tf float64) Components {
+	comp := m.steady(m.Rider.CdA, va, vg, gr)
+	comp.KE = m.pke(vgi, vgf, ti, tf)
+	return comp
+}
+
+// pke calculates Pke divided by the drive chain efficiency given the initial
+// and final ground velocities vgi and vgf at times ti and tf, ignoring the
+// inertia of the wheels if the tire radius is unknown.
+func (m Model) pke(vgi, vgf, ti, tf float64) float64 {
+	b := m.Bike
+	if b.TireRadius == 0 {
+		return Pke(m.Mass(), 0, 1, vgi, vgf, ti, tf) / b.DrivetrainEfficiency
+	}
+	return Pke(m.Mass(), b.WheelInertia, b.TireRadius, vgi, vgf, ti, tf) / b.DrivetrainEfficiency
+}
+
+// steady calculates the components of power given an explicit CdA cda, air
+// velocity va, ground velocity vg and road gradient gr, without any
+// contribution from Pke.
+func (m Model) steady(cda, va, vg, gr float64) Components {
+	rho, g, mt := m.Environment.Rho, m.Environment.G, m.Mass()
+	b := m.Bike
+	ec := b.DrivetrainEfficiency
+	return Components{
+		AT: Pat(rho, cda, b.Fw, va, vg) / ec,
+		RR: Prr(vg, gr, b.Crr, mt, g) / ec,
+		WB: Pwb(vg) / ec,
+		PE: Ppe(vg, mt, g, gr) / ec,
+	}
+}
+
+// positional returns the Model corresponding to the arguments of the
+// positional functions of this package, where only the total mass mt is known.
+func positional(rho, cda, crr, vw, dw, mt, r, g, ec, fw, i float64) Model {
+	return Model{
+		Rider: Rider{Mass: mt, CdA: cda},
+		Bike: Bike{
+			Crr:                  crr,
+			TireRadius:           r,
+			WheelInertia:         i,
+			DrivetrainEfficiency: ec,
+			Fw:                   fw,
+		},
+		Environment: Environment{Rho: rho, G: g, Wind: Wind{Speed: vw, Direction: dw}},
+	}
+}
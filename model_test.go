@@ -0,0 +1,84 @@
+package calc
+
+import (
+	"testing"
+)
+
+func testModel(mr, mb, cda, crr, rho, vw, dw float64) Model {
+	return Model{
+		Rider: Rider{Mass: mr, CdA: cda},
+		Bike: Bike{
+			Mass:                 mb,
+			Crr:                  crr,
+			TireRadius:           R700x23,
+			WheelInertia:         I,
+			DrivetrainEfficiency: Ec,
+			Fw:                   Fw,
+		},
+		Environment: Environment{Rho: rho, G: G, Wind: Wind{Speed: vw, Direction: dw}},
+	}
+}
+
+func TestModelPower(t *testing.T) {
+	tests := []struct {
+		m          Model
+		vg, gr, db float64
+		expected   float64
+	}{
+		{testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0), 5.55, 0.08125, 0, 389.9},
+		{testModel(75, 10, TopsCdA, 0.008, 1.1921, 2.7778, 180), 4.293, 0.079, 45,
+			Psimp(1.1921, TopsCdA, 0.008, Va(4.293, 2.7778, 180, 45), 4.293, 0.079, 85, G, Ec, Fw)},
+	}
+	for _, tt := range tests {
+		actual := tt.m.Power(tt.vg, Conditions{Grade: tt.gr, Heading: tt.db})
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("%+v.Power(%.3f, {%.3f, %.3f}): got: %.3f, want: %.3f",
+				tt.m, tt.vg, tt.gr, tt.db, actual, tt.expected)
+		}
+	}
+}
+
+func TestModelSpeed(t *testing.T) {
+	tests := []struct {
+		m         Model
+		p, gr, db float64
+		expected  float64
+	}{
+		{testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0), 389.9, 0.08125, 0, 5.55},
+		{testModel(75, 10, TopsCdA, 0.008, 1.1921, 2.7778, 180), 340.0, 0.079, 45,
+			Vg(340.0, 1.1921, TopsCdA, 0.008, 2.7778, 180, 45, 0.079, 85, G, Ec, Fw)},
+		{testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0), 0, -0.08, 0, 16.974},
+		{testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0), 0, 0, 0, 0},
+		{testModel(67, 8, TTAeroCdA, Crr, Rho0, 0, 0), 250000, 0, 0, 116.05},
+		{testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0), -100, -0.08, 0, 16.016},
+	}
+	for _, tt := range tests {
+		actual, err := tt.m.Speed(tt.p, Conditions{Grade: tt.gr, Heading: tt.db})
+		if err != nil || !Eqf(actual, tt.expected) {
+			t.Errorf("%+v.Speed(%.3f, {%.3f, %.3f}): got: %.3f, want: %.3f",
+				tt.m, tt.p, tt.gr, tt.db, actual, tt.expected)
+		}
+	}
+}
+
+func TestModelSpeedNoSolution(t *testing.T) {
+	crosswind := testModel(67, 8, DropsCdA, Crr, Rho0, 10, 90)
+	crosswind.Rider.Curve = TabulatedCdA{{Yaw: 0, CdA: 1}, {Yaw: 40, CdA: 1}, {Yaw: 41, CdA: 0.1}}
+	tests := []struct {
+		m     Model
+		p, gr float64
+	}{
+		{testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0), -100, 0},
+		{testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0), -1000, -0.01},
+		// the CdA drops so sharply with yaw in the crosswind that the velocity
+		// oscillates instead of converging
+		{crosswind, 200, 0},
+	}
+	for _, tt := range tests {
+		actual, err := tt.m.Speed(tt.p, Conditions{Grade: tt.gr})
+		if err != ErrNoSolution {
+			t.Errorf("%+v.Speed(%.3f, {%.3f}): got: %.3f, want: %v",
+				tt.m, tt.p, tt.gr, actual, ErrNoSolution)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Distribution is a probability distribution which values can be sampled from.
+type Distribution interface {
+	// Sample returns a random value drawn from the distribution using r.
+	Sample(r *rand.Rand) float64
+}
+
+// Normal is the normal distribution with a Mean and standard deviation StdDev.
+type Normal struct {
+	Mean, StdDev float64
+}
+
+// Sample returns a random value drawn from the distribution using r.
+func (n Normal) Sample(r *rand.Rand) float64 {
+	return n.Mean + n.StdDev*r.NormFloat64()
+}
+
+// Uniform is the continuous uniform distribution between Min and Max.
+type Uniform struct {
+	Min, Max float64
+}
+
+// Sample returns a random value drawn from the distribution using r.
+func (u Uniform) Sample(r *rand.Rand) float64 {
+	return u.Min + (u.Max-u.Min)*r.Float64()
+}
+
+// Triangular is the triangular distribution between Min and Max with a peak at
+// Mode.
+type Triangular struct {
+	Min, Mode, Max float64
+}
+
+// Sample returns a random value drawn from the distribution using r.
+func (t Triangular) Sample(r *rand.Rand) float64 {
+	u := r.Float64()
+	w := t.Max - t.Min
+	if w == 0 {
+		return t.Mode
+	}
+	f := (t.Mode - t.Min) / w
+	if u < f {
+		return t.Min + math.Sqrt(u*w*(t.Mode-t.Min))
+	}
+	return t.Max - math.Sqrt((1-u)*w*(t.Max-t.Mode))
+}
+
+// Uncertainty describes the uncertainty in the inputs of a prediction.
+type Uncertainty struct {
+	// Variables are the distributions of each uncertain Variable. Variables
+	// which are not present take their value from the Model.
+	Variables map[Variable]Distribution
+	// Power is the distribution of the net total power in watts. If nil, the
+	// power is known exactly.
+	Power Distribution
+	// Samples is the number of predictions to make.
+	Samples int
+	// Seed seeds the random number generator used to sample the inputs, such
+	// that the same Seed always produces the same Forecast.
+	Seed int64
+}
+
+// Forecast is the distribution of the times predicted under Uncertainty.
+type Forecast struct {
+	// Times are the predicted times in seconds in increasing order.
+	Times []float64
+	// Mean is the mean time in seconds.
+	Mean float64
+	// StdDev is the standard deviation of the time in seconds.
+	StdDev float64
+	// Failures is the number of samples for which the course could not be
+	// completed.
+	Failures int
+}
+
+// Bin is a bin of a histogram of times.
+type Bin struct {
+	// Min and Max are the range of times in seconds of the bin.
+	Min, Max float64
+	// Count is the number of times which fall into the bin.
+	Count int
+}
+
+// Forecast predicts the distribution of the time taken by Model m to ride the
+// course at a constant net total power of p watts given the Uncertainty u, using
+// Monte Carlo simulation. The samples are evaluated in parallel.
+func (c Course) Forecast(m Model, p float64, u Uncertainty) (Forecast, error) {
+	if u.Samples <= 0 {
+		return Forecast{}, fmt.Errorf("samples must be positive but was %d", u.Samples)
+	}
+	if len(c) == 0 {
+		return Forecast{}, fmt.Errorf("course has no segments")
+	}
+
+	// sample all of the inputs up front (in a fixed order) so the result
+	// doesn't depend on the order the samples are evaluated in
+	rng := rand.New(rand.NewSource(u.Seed))
+	models := make([]Model, u.Samples)
+	courses := make([]Course, u.Samples)
+	powers := make([]float64, u.Samples)
+	for i := range models {
+		models[i], courses[i], powers[i] = m, c, p
+		for v := range variables {
+			if d, ok := u.Variables[Variable(v)]; ok && d != nil {
+				models[i], courses[i] = Variable(v).Set(models[i], courses[i], d.Sample(rng))
+			}
+		}
+		if u.Power != nil {
+			powers[i] = u.Power.Sample(rng)
+		}
+	}
+
+	times := make([]float64, u.Samples)
+	workers := runtime.GOMAXPROCS(0)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < u.Samples; i += workers {
+				r, err := courses[i].Time(models[i], powers[i])
+				if err != nil {
+					times[i] = math.NaN()
+				} else {
+					times[i] = r.Time
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var f Forecast
+	for _, t := range times {
+		if math.IsNaN(t) {
+			f.Failures++
+			continue
+		}
+		f.Times = append(f.Times, t)
+		f.Mean += t
+	}
+	if len(f.Times) == 0 {
+		return f, ErrNoSolution
+	}
+	sort.Float64s(f.Times)
+
+	n := float64(len(f.Times))
+	f.Mean /= n
+	for _, t := range f.Times {
+		f.StdDev += (t - f.Mean) * (t - f.Mean) / n
+	}
+	f.StdDev = math.Sqrt(f.StdDev)
+	return f, nil
+}
+
+// Percentile returns the q-th percentile (0-100) of the times in seconds,
+// linearly interpolating between samples.
+func (f Forecast) Percentile(q float64) float64 {
+	if len(f.Times) == 0 {
+		return math.NaN()
+	}
+	x := q / 100 * float64(len(f.Times)-1)
+	i := int(math.Max(0, math.Min(math.Floor(x), float64(len(f.Times)-1))))
+	if i == len(f.Times)-1 {
+		return f.Times[i]
+	}
+	return f.Times[i] + (f.Times[i+1]-f.Times[i])*(x-float64(i))
+}
+
+// Histogram divides the range of times into n bins of equal width, returning
+// the number of times which fall into each.
+func (f Forecast) Histogram(n int) []Bin {
+	if len(f.Times) == 0 || n <= 0 {
+		return nil
+	}
+
+	min, max := f.Times[0], f.Times[len(f.Times)-1]
+	w := (max - min) / float64(n)
+	bins := make([]Bin, n)
+	for i := range bins {
+		bins[i] = Bin{Min: min + float64(i)*w, Max: min + float64(i+1)*w}
+	}
+	for _, t := range f.Times {
+		i := n - 1
+		if w > 0 {
+			i = int(math.Min(float64(n-1), (t-min)/w))
+		}
+		bins[i].Count++
+	}
+	return bins
+}
@@ -0,0 +1,110 @@
+package calc
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDistributions(t *testing.T) {
+	tests := []struct {
+		d        Distribution
+		mean, sd float64
+		min, max float64
+	}{
+		{Normal{Mean: 0.3, StdDev: 0.01}, 0.3, 0.01, math.Inf(-1), math.Inf(1)},
+		{Uniform{Min: 2, Max: 4}, 3, 2 / math.Sqrt(12), 2, 4},
+		{Triangular{Min: 0, Mode: 1, Max: 5}, 2, math.Sqrt((0 + 1 + 25 - 0 - 0 - 5) / 18.0), 0, 5},
+		{Triangular{Min: 1, Mode: 1, Max: 1}, 1, 0, 1, 1},
+	}
+	for _, tt := range tests {
+		r := rand.New(rand.NewSource(1))
+		n := 100000
+		mean, sq := 0.0, 0.0
+		for i := 0; i < n; i++ {
+			x := tt.d.Sample(r)
+			if x < tt.min || x > tt.max {
+				t.Fatalf("%+v.Sample: got: %.3f, want: [%.3f, %.3f]", tt.d, x, tt.min, tt.max)
+			}
+			mean += x / float64(n)
+			sq += x * x / float64(n)
+		}
+		sd := math.Sqrt(math.Max(0, sq-mean*mean))
+		if !Eqf(mean, tt.mean, 1e-2) || !(Eqf(sd, tt.sd, 2e-2) || tt.sd == 0 && sd < 1e-3) {
+			t.Errorf("%+v.Sample: got: mean %.4f sd %.4f, want: mean %.4f sd %.4f", tt.d, mean, sd, tt.mean, tt.sd)
+		}
+	}
+}
+
+func TestCourseForecast(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Course{{Length: 20000, Grade: 0.01}}
+	p := 250.0
+	r, err := c.Time(m, p)
+	if err != nil {
+		t.Fatalf("%v.Time(%+v, %.3f): got: %v", c, m, p, err)
+	}
+
+	f, err := c.Forecast(m, p, Uncertainty{Samples: 10})
+	if err != nil || len(f.Times) != 10 || !Eqf(f.Mean, r.Time) || f.StdDev > 1e-9 {
+		t.Errorf("%v.Forecast(%+v, %.3f, {}): got: %+v (%v), want: %.3f", c, m, p, f, err, r.Time)
+	}
+
+	u := Uncertainty{
+		Variables: map[Variable]Distribution{
+			VarCdA:  Normal{Mean: DropsCdA, StdDev: 0.01},
+			VarCrr:  Uniform{Min: 0.003, Max: 0.005},
+			VarWind: Triangular{Min: 0, Mode: 1, Max: 3},
+		},
+		Samples: 2000,
+		Seed:    42,
+	}
+	f, err = c.Forecast(m, p, u)
+	if err != nil || f.Failures != 0 || len(f.Times) != u.Samples {
+		t.Fatalf("%v.Forecast(%+v, %.3f, %+v): got: %d times, %d failures (%v)",
+			c, m, p, u, len(f.Times), f.Failures, err)
+	}
+	p10, p50, p90 := f.Percentile(10), f.Percentile(50), f.Percentile(90)
+	if !(p10 < p50 && p50 < p90) || p10 < r.Time || f.StdDev <= 0 {
+		t.Errorf("%v.Forecast(%+v, %.3f, %+v): got: P10 %.3f P50 %.3f P90 %.3f, want: increasing from %.3f",
+			c, m, p, u, p10, p50, p90, r.Time)
+	}
+	g, _ := c.Forecast(m, p, u)
+	for i := range f.Times {
+		if f.Times[i] != g.Times[i] {
+			t.Errorf("%v.Forecast(%+v, %.3f, %+v): got: different results for the same seed", c, m, p, u)
+			break
+		}
+	}
+
+	u = Uncertainty{Power: Uniform{Min: -50, Max: 250}, Samples: 100}
+	if f, err := c.Forecast(m, p, u); err != nil || f.Failures == 0 || f.Failures+len(f.Times) != u.Samples {
+		t.Errorf("%v.Forecast(%+v, %.3f, %+v): got: %d failures (%v), want: > 0", c, m, p, u, f.Failures, err)
+	}
+	if _, err := c.Forecast(m, p, Uncertainty{}); err == nil {
+		t.Errorf("%v.Forecast(%+v, %.3f, {}): expected error", c, m, p)
+	}
+}
+
+func TestForecastPercentileHistogram(t *testing.T) {
+	f := Forecast{Times: []float64{1, 2, 3, 4, 5}}
+	tests := []struct {
+		q, expected float64
+	}{
+		{0, 1},
+		{10, 1.4},
+		{50, 3},
+		{100, 5},
+	}
+	for _, tt := range tests {
+		if actual := f.Percentile(tt.q); !Eqf(actual, tt.expected) {
+			t.Errorf("%+v.Percentile(%.3f): got: %.3f, want: %.3f", f, tt.q, actual, tt.expected)
+		}
+	}
+
+	expected := []Bin{{1, 3, 2}, {3, 5, 3}}
+	actual := f.Histogram(2)
+	if len(actual) != len(expected) || actual[0] != expected[0] || actual[1] != expected[1] {
+		t.Errorf("%+v.Histogram(2): got: %v, want: %v", f, actual, expected)
+	}
+}
@@ -0,0 +1,251 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Pacing is a variable power strategy for riding a Course.
+type Pacing struct {
+	// Result is the predicted performance following the strategy, where the
+	// Power of each Split is the power target for the segment.
+	Result
+	// Even is the predicted performance at the highest constant power which
+	// does not exhaust W′.
+	Even Result
+	// Gain is the time in seconds saved compared to Even.
+	Gain float64
+	// WBal is the minimum W′bal in joules reached following the strategy.
+	WBal float64
+}
+
+// Pace optimizes the power of Model m over each segment of the course to
+// minimize the total time without exhausting the W′ of the rider as described
+// by cp and the Recovery model r. Optimal pacing spends more power where it
+// buys the most time, e.g. on climbs and into headwinds, and less where the
+// speed is already high, e.g. on descents and with tailwinds, where the power
+// targets may drop below CP to allow W′ to be reconstituted. Power targets are
+// in whole watts. The Pacing is never slower than riding at an even power.
+// ErrNoSolution is returned if the course can't be completed at CP.
+func (c Course) Pace(m Model, cp CriticalPower, r Recovery) (Pacing, error) {
+	// iterations is the number of bisections of the Lagrange multiplier
+	const iterations = 20
+	// floors is the number of power floors considered
+	const floors = 2
+	// weights is the number of weights of the power below the floor considered
+	const weights = 8
+
+	if len(c) == 0 {
+		return Pacing{}, fmt.Errorf("course has no segments")
+	}
+
+	even, err := c.sustainable(m, cp, r)
+	if err != nil {
+		return Pacing{}, err
+	}
+
+	// The time taken to ride each segment only depends on the power, so it is
+	// cached for every whole watt considered to avoid solving for the same
+	// speeds repeatedly while searching for the Lagrange multiplier.
+	times := make([]func(p int) float64, len(c))
+	for i, s := range c {
+		time, cache := m.segment(s).time(s), make(map[int]float64)
+		times[i] = func(p int) float64 {
+			t, ok := cache[p]
+			if !ok {
+				t = time(float64(p))
+				cache[p] = t
+			}
+			return t
+		}
+	}
+	// Riding a segment at p watts expends (p - CP) * t(p) joules of W′
+	// regardless of the Recovery model, so the power target of each segment
+	// can't be higher than the power which expends all of W′ even starting
+	// fully recovered. max is the highest of these ceilings in watts.
+	ceilings, max := make([]int, len(c)), 0
+	for i, s := range c {
+		t, lo := times[i], int(cp.CP)
+		if s.Length > 0 {
+			hi := 2*lo + 1
+			for (float64(hi)-cp.CP)*t(hi) <= cp.WPrime {
+				lo, hi = hi, 2*hi
+			}
+			for hi-lo > 1 {
+				mid := (lo + hi) / 2
+				if (float64(mid)-cp.CP)*t(mid) <= cp.WPrime {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+		}
+		ceilings[i] = lo
+		if lo > max {
+			max = lo
+		}
+	}
+
+	// Minimizing the time for a fixed expenditure of W′ requires minimizing
+	// t_i(P) * (1 + μ * (P - F)) for each segment for some Lagrange
+	// multiplier μ, where F is the power below which W′ is reconstituted. This
+	// is CP, but as W′ is reconstituted more slowly than it is expended (and
+	// never beyond W′) a range of F between CP and the even power and of
+	// weights k for the power below F are considered. Less W′ is expended as μ
+	// increases, so we search for the smallest μ which does not exhaust W′. μ
+	// is kept below 1/F so that the objective remains positive even when
+	// coasting. The optimal power of each segment can only decrease as μ
+	// increases, so the powers planned for the bounds of μ bracket the search
+	// for the powers in between.
+	plan := func(floor, k, mu float64, lower, upper []int) ([]int, Result, float64, error) {
+		powers := make([]int, len(c))
+		res := Result{Splits: make([]Split, len(c))}
+		for i, s := range c {
+			t := times[i]
+			powers[i] = argmin(lower[i], upper[i], func(p int) float64 {
+				w := float64(p) - floor
+				if w < 0 {
+					w *= k
+				}
+				return t(p) * (1 + mu*w)
+			})
+			res.Splits[i] = Split{Segment: s, Time: t(powers[i]), Power: float64(powers[i])}
+			res.Time += res.Splits[i].Time
+		}
+		if math.IsInf(res.Time, 0) {
+			return powers, Result{}, 0, ErrNoSolution
+		}
+		min, err := cp.Check(res, r)
+		return powers, res, min, err
+	}
+
+	best := Pacing{Result: even, Even: even}
+	best.WBal, _ = cp.Check(even, r)
+
+	var targets []int
+	for f := 0.0; f < floors; f++ {
+		floor := cp.CP + (even.Power-cp.CP)*f/floors
+		for k := 0.0; k < weights; k++ {
+			lower, upper := make([]int, len(c)), append([]int(nil), ceilings...)
+			// lo and hi are the range of the base 10 logarithm of μ, where
+			// below 1/(2*max + F) even segments dominated by aerodynamic drag
+			// would be ridden at the maximum power
+			lo, hi := -math.Log10(2*float64(max)+floor), math.Min(0, -math.Log10(floor))
+			for j := 0; j < iterations && !equal(lower, upper); j++ {
+				mid := (lo + hi) / 2
+				powers, res, min, err := plan(floor, k/weights, math.Pow(10, mid), lower, upper)
+				if err == ErrExhausted {
+					lo, upper = mid, powers
+					continue
+				} else if err != nil {
+					// the course can't be completed with powers this low
+					hi = mid
+					continue
+				}
+				hi, lower = mid, powers
+				if res.Time < best.Time {
+					targets, best.Time, best.WBal = powers, res.Time, min
+				}
+			}
+		}
+	}
+
+	if targets != nil {
+		res, err := c.pace(m, func(i int) float64 { return float64(targets[i]) })
+		if err != nil {
+			return Pacing{}, err
+		}
+		best.Result = res
+	}
+	best.Gain = even.Time - best.Time
+	return best, nil
+}
+
+// sustainable returns the performance over the course at the highest constant
+// power which doesn't exhaust W′.
+func (c Course) sustainable(m Model, cp CriticalPower, r Recovery) (Result, error) {
+	// epsilon is the relative precision of the power
+	const epsilon = 1e-9
+	// max is the maximum power in watts which will be considered
+	const max = 1e6
+
+	ok := func(p float64) bool {
+		res, err := c.Time(m, p)
+		if err != nil {
+			return false
+		}
+		_, err = cp.Check(res, r)
+		return err == nil
+	}
+
+	// W′ is never depleted at CP, so the even power is at least CP and the
+	// course can't be completed at an even power if it can't be completed at CP
+	pl := cp.CP
+	if _, err := c.Time(m, pl); err != nil {
+		return Result{}, err
+	}
+	ph := 2 * pl
+	for ok(ph) {
+		pl, ph = ph, 2*ph
+		if ph > max {
+			return Result{}, ErrNoSolution
+		}
+	}
+	for ph-pl > epsilon*ph {
+		pm := (pl + ph) / 2
+		if ok(pm) {
+			pl = pm
+		} else {
+			ph = pm
+		}
+	}
+	return c.Time(m, pl)
+}
+
+// time returns a function which calculates the time in seconds taken by the
+// Model m to ride the Segment s at p watts, or +Inf if it cannot.
+func (m Model) time(s Segment) func(p float64) float64 {
+	speed := m.speed(s.Conditions())
+	return func(p float64) float64 {
+		if s.Length == 0 {
+			return 0
+		}
+		vg, err := speed(p)
+		if err != nil || vg == 0 {
+			return math.Inf(1)
+		}
+		return s.Length / vg
+	}
+}
+
+// equal returns whether the powers a and b are the same.
+func equal(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// argmin returns the integer x in [a, b] which minimizes the unimodal function f
+// using a golden section search.
+func argmin(a, b int, f func(x int) float64) int {
+	phi := (math.Sqrt(5) - 1) / 2
+	for b-a > 4 {
+		c := b - int(math.Round(phi*float64(b-a)))
+		d := a + (b - c)
+		if f(c) < f(d) {
+			b = d
+		} else {
+			a = c
+		}
+	}
+	x := a
+	for i := a + 1; i <= b; i++ {
+		if f(i) < f(x) {
+			x = i
+		}
+	}
+	return x
+}
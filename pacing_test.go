@@ -0,0 +1,93 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCoursePace(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 4, 0)
+	cp := CriticalPower{CP: 280, WPrime: 20000}
+	c := Course{
+		{Length: 5000, Grade: 0, Heading: 0},
+		{Length: 2000, Grade: 0.06, Heading: 0},
+		{Length: 2000, Grade: -0.06, Heading: 180},
+		{Length: 5000, Grade: 0, Heading: 180},
+	}
+
+	for _, r := range []Recovery{SkibaDifferential, SkibaIntegral, Bartram} {
+		actual, err := c.Pace(m, cp, r)
+		if err != nil {
+			t.Errorf("%v.Pace(%+v, %+v, %d): got: %v", c, m, cp, r, err)
+			continue
+		}
+		if actual.Gain <= 0 || !Eqf(actual.Gain, actual.Even.Time-actual.Time) {
+			t.Errorf("%v.Pace(%+v, %+v, %d): got: %.3f s gain, want: > 0", c, m, cp, r, actual.Gain)
+		}
+		if min, err := cp.Check(actual.Result, r); err != nil || !Eqf(min, actual.WBal) {
+			t.Errorf("%v.Pace(%+v, %+v, %d): got: %.3f J W′bal (%v), want: %.3f J",
+				c, m, cp, r, min, err, actual.WBal)
+		}
+		if climb, descent := actual.Splits[1].Power, actual.Splits[2].Power; climb <= descent {
+			t.Errorf("%v.Pace(%+v, %+v, %d): got: %.3f W climbing and %.3f W descending, want: more climbing",
+				c, m, cp, r, climb, descent)
+		}
+	}
+
+	if _, err := (Course{}).Pace(m, cp, SkibaDifferential); err == nil {
+		t.Errorf("Course{}.Pace(%+v, %+v, %d): expected error", m, cp, SkibaDifferential)
+	}
+}
+
+func TestCoursePaceEven(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	cp := CriticalPower{CP: 280, WPrime: 20000}
+	c := Course{{Length: 5000}, {Length: 5000}}
+
+	actual, err := c.Pace(m, cp, SkibaDifferential)
+	if err != nil || !Eqf(actual.Gain, 0) || !Eqf(actual.Power, actual.Even.Power) {
+		t.Errorf("%v.Pace(%+v, %+v): got: %.3f W with %.3f s gain (%v), want: %.3f W with 0 s gain",
+			c, m, cp, actual.Power, actual.Gain, err, actual.Even.Power)
+	}
+	// the even power exhausts W′ exactly over the course
+	if expected := cp.CP + cp.WPrime/actual.Even.Time; !Eqf(actual.Even.Power, expected) {
+		t.Errorf("%v.Pace(%+v, %+v): got: %.3f W even, want: %.3f W", c, m, cp, actual.Even.Power, expected)
+	}
+}
+
+func TestCoursePaceBelowCP(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	cp := CriticalPower{CP: 280, WPrime: 20000}
+	c := Course{
+		{Length: 3000, Grade: 0.07},
+		{Length: 3000, Grade: -0.07},
+		{Length: 3000, Grade: 0.07},
+		{Length: 3000, Grade: -0.07},
+	}
+
+	actual, err := c.Pace(m, cp, SkibaIntegral)
+	if err != nil || actual.Gain <= 0 {
+		t.Fatalf("%v.Pace(%+v, %+v): got: %.3f s gain (%v), want: > 0", c, m, cp, actual.Gain, err)
+	}
+	// recovering on the descents allows for more power on the climbs
+	for i, s := range actual.Splits {
+		if s.Grade < 0 && s.Power >= cp.CP || s.Grade > 0 && s.Power <= actual.Even.Power {
+			t.Errorf("%v.Pace(%+v, %+v): got: %.3f W on segment %d, want: %s",
+				c, m, cp, s.Power, i, map[bool]string{true: "< CP", false: "> even"}[s.Grade < 0])
+		}
+	}
+}
+
+func BenchmarkCoursePace(b *testing.B) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 4, 0)
+	cp := CriticalPower{CP: 280, WPrime: 20000}
+	c := make(Course, 2000)
+	for i := range c {
+		c[i] = Segment{Length: 20, Grade: 0.05 * math.Sin(float64(i)/50), Heading: float64(i % 360)}
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Pace(m, cp, SkibaDifferential); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package calc
+
+import (
+	"math"
+	"sort"
+)
+
+// cubic returns the real roots of a*x^3 + b*x^2 + c*x + d in ascending order,
+// falling back to the quadratic or linear solution when the higher order
+// coefficients vanish.
+func cubic(a, b, c, d float64) []float64 {
+	if math.Abs(a) <= 1e-12*math.Max(math.Abs(b), math.Max(math.Abs(c), math.Abs(d))) {
+		return quadratic(b, c, d)
+	}
+
+	b, c, d = b/a, c/a, d/a
+	// substitute x = t - b/3 to get the depressed cubic t^3 + p*t + q
+	p := c - b*b/3
+	q := 2*b*b*b/27 - b*c/3 + d
+	shift := -b / 3
+
+	var roots []float64
+	disc := q*q/4 + p*p*p/27
+	if disc > 0 {
+		s := math.Sqrt(disc)
+		roots = []float64{math.Cbrt(-q/2+s) + math.Cbrt(-q/2-s) + shift}
+	} else if p == 0 {
+		roots = []float64{shift}
+	} else {
+		r := 2 * math.Sqrt(-p/3)
+		phi := math.Acos(math.Max(-1, math.Min(1, 3*q/(p*r))))
+		for k := 0.0; k < 3; k++ {
+			roots = append(roots, r*math.Cos(phi/3-2*math.Pi*k/3)+shift)
+		}
+	}
+
+	// polish the roots with Newton's method to recover precision lost above
+	for i, x := range roots {
+		for j := 0; j < 3; j++ {
+			f := ((x+b)*x+c)*x + d
+			df := (3*x+2*b)*x + c
+			if df == 0 {
+				break
+			}
+			x -= f / df
+		}
+		roots[i] = x
+	}
+
+	sort.Float64s(roots)
+	return roots
+}
+
+// quadratic returns the real roots of a*x^2 + b*x + c in ascending order.
+func quadratic(a, b, c float64) []float64 {
+	if math.Abs(a) <= 1e-12*math.Max(math.Abs(b), math.Abs(c)) {
+		if b == 0 {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return nil
+	}
+
+	// avoid catastrophic cancellation by computing the larger root first
+	q := -0.5 * (b + math.Copysign(math.Sqrt(disc), b))
+	if q == 0 {
+		return []float64{0, 0}
+	}
+	roots := []float64{q / a, c / q}
+	sort.Float64s(roots)
+	return roots
+}
@@ -0,0 +1,34 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestCubic(t *testing.T) {
+	tests := []struct {
+		a, b, c, d float64
+		expected   []float64
+	}{
+		{1, -6, 11, -6, []float64{1, 2, 3}},
+		{2, 0, 0, -16, []float64{2}},
+		{0, 1, -3, 2, []float64{1, 2}},
+		{0, 0, 2, -1, []float64{0.5}},
+		{1, 0, 0, 0, []float64{0}},
+		{0, 1, 0, 1, nil},
+	}
+	for _, tt := range tests {
+		actual := cubic(tt.a, tt.b, tt.c, tt.d)
+		if len(actual) != len(tt.expected) {
+			t.Errorf("cubic(%.3f, %.3f, %.3f, %.3f): got: %v, want: %v",
+				tt.a, tt.b, tt.c, tt.d, actual, tt.expected)
+			continue
+		}
+		for i := range actual {
+			if !Eqf(actual[i], tt.expected[i]) {
+				t.Errorf("cubic(%.3f, %.3f, %.3f, %.3f): got: %v, want: %v",
+					tt.a, tt.b, tt.c, tt.d, actual, tt.expected)
+				break
+			}
+		}
+	}
+}
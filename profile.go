@@ -0,0 +1,182 @@
+package calc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// radii maps from standard road cycling tire widths in mm to their radius.
+var radii = map[int]float64{
+	20: R700x20,
+	22: R700x22,
+	23: R700x23,
+	25: R700x25,
+	28: R700x28,
+}
+
+// RiderProfile describes a rider whose measurements are stored in a profile.
+// Zero values are considered to be unspecified.
+type RiderProfile struct {
+	// Mass is the mass of the rider in kg.
+	Mass float64 `json:"mass"`
+	// Height is the height of the rider in metres.
+	Height float64 `json:"height"`
+	// FTP is the functional threshold power of the rider in watts.
+	FTP float64 `json:"ftp"`
+	// CdA is the CdA of the rider in each of their positions (e.g. 'hoods' or
+	// 'tt'), in squared metres.
+	CdA map[string]float64 `json:"cda"`
+	// Position is the default position of the rider.
+	Position string `json:"position"`
+}
+
+// PositionCdA returns the CdA of the rider in the position, or in their default
+// position if empty. If there is no default and only a single position, its CdA
+// is returned.
+func (r RiderProfile) PositionCdA(position string) (float64, error) {
+	if position == "" {
+		position = r.Position
+	}
+	if position == "" {
+		if len(r.CdA) != 1 {
+			return 0, fmt.Errorf("position must be specified for a profile with %d positions", len(r.CdA))
+		}
+		for _, cda := range r.CdA {
+			return cda, nil
+		}
+	}
+	cda, ok := r.CdA[position]
+	if !ok {
+		return 0, fmt.Errorf("no CdA for position '%s'", position)
+	}
+	return cda, nil
+}
+
+// Rider returns the Rider described by the profile in the position (see
+// PositionCdA).
+func (r RiderProfile) Rider(position string) (Rider, error) {
+	cda, err := r.PositionCdA(position)
+	if err != nil {
+		return Rider{}, err
+	}
+	return Rider{Mass: r.Mass, CdA: cda}, nil
+}
+
+// BikeProfile describes a bicycle whose equipment is stored in a profile. Zero
+// values are considered to be unspecified.
+type BikeProfile struct {
+	// Mass is the mass of the bicycle in kg.
+	Mass float64 `json:"mass"`
+	// Tire is the width of the tires in mm (20, 22, 23, 25 or 28).
+	Tire int `json:"tire"`
+	// Crr is the coefficient of rolling resistance of the tires.
+	Crr float64 `json:"crr"`
+	// DrivetrainEfficiency is the drive chain efficiency factor.
+	DrivetrainEfficiency float64 `json:"ec"`
+}
+
+// Bike returns the Bike described by the profile, where unspecified values
+// default to the typical values (Crr, R700x23, I, Ec and Fw).
+func (b BikeProfile) Bike() (Bike, error) {
+	bike := Bike{
+		Mass:                 b.Mass,
+		Crr:                  b.Crr,
+		TireRadius:           R700x23,
+		WheelInertia:         I,
+		DrivetrainEfficiency: b.DrivetrainEfficiency,
+		Fw:                   Fw,
+	}
+	if b.Tire != 0 {
+		r, ok := radii[b.Tire]
+		if !ok {
+			return Bike{}, fmt.Errorf("invalid tire width %d", b.Tire)
+		}
+		bike.TireRadius = r
+	}
+	if bike.Crr == 0 {
+		bike.Crr = Crr
+	}
+	if bike.DrivetrainEfficiency == 0 {
+		bike.DrivetrainEfficiency = Ec
+	}
+	return bike, nil
+}
+
+// Profiles are the named rider and bike profiles stored in a config file.
+type Profiles struct {
+	Riders map[string]RiderProfile `json:"riders"`
+	Bikes  map[string]BikeProfile  `json:"bikes"`
+}
+
+// Rider returns the named rider profile.
+func (p Profiles) Rider(name string) (RiderProfile, error) {
+	r, ok := p.Riders[name]
+	if !ok {
+		return RiderProfile{}, fmt.Errorf("no rider profile '%s'", name)
+	}
+	return r, nil
+}
+
+// Bike returns the named bike profile.
+func (p Profiles) Bike(name string) (BikeProfile, error) {
+	b, ok := p.Bikes[name]
+	if !ok {
+		return BikeProfile{}, fmt.Errorf("no bike profile '%s'", name)
+	}
+	return b, nil
+}
+
+// ReadProfiles parses Profiles from JSON data read from r, e.g.
+//
+//	{
+//	  "riders": {"alice": {"mass": 60, "cda": {"hoods": 0.32, "tt": 0.22}}},
+//	  "bikes": {"tt": {"mass": 8.5, "tire": 23, "crr": 0.0035}}
+//	}
+func ReadProfiles(r io.Reader) (Profiles, error) {
+	var p Profiles
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return Profiles{}, err
+	}
+	return p, nil
+}
+
+// ProfilesPath returns the path of the default profiles file, profiles.json in
+// the 'calc' directory of $XDG_CONFIG_HOME (or $HOME/.config if unset).
+func ProfilesPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return "", fmt.Errorf("neither $XDG_CONFIG_HOME nor $HOME are defined")
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "calc", "profiles.json"), nil
+}
+
+// LoadProfiles reads the Profiles from the file at path, or from ProfilesPath
+// if path is empty.
+func LoadProfiles(path string) (Profiles, error) {
+	if path == "" {
+		var err error
+		if path, err = ProfilesPath(); err != nil {
+			return Profiles{}, err
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return Profiles{}, err
+	}
+	defer f.Close()
+
+	p, err := ReadProfiles(f)
+	if err != nil {
+		return Profiles{}, fmt.Errorf("unable to read profiles file '%s': %s", path, err)
+	}
+	return p, nil
+}
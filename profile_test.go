@@ -0,0 +1,127 @@
+package calc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profiles = `{
+  "riders": {
+    "alice": {"mass": 60, "height": 1.68, "ftp": 250, "cda": {"hoods": 0.32, "tt": 0.22}, "position": "hoods"},
+    "bob": {"mass": 75, "cda": {"drops": 0.3}}
+  },
+  "bikes": {
+    "tt": {"mass": 8.5, "tire": 25, "crr": 0.0035, "ec": 0.98},
+    "road": {"mass": 7.5}
+  }
+}`
+
+func TestReadProfiles(t *testing.T) {
+	p, err := ReadProfiles(strings.NewReader(profiles))
+	if err != nil {
+		t.Fatalf("ReadProfiles: got: %v", err)
+	}
+
+	alice, err := p.Rider("alice")
+	if err != nil || alice.Mass != 60 || alice.Height != 1.68 || alice.FTP != 250 || len(alice.CdA) != 2 {
+		t.Errorf("Rider(alice): got: %+v (%v)", alice, err)
+	}
+	if _, err := p.Rider("carol"); err == nil {
+		t.Errorf("Rider(carol): got: nil, want: error")
+	}
+	tt, err := p.Bike("tt")
+	if err != nil || tt.Mass != 8.5 || tt.Tire != 25 || tt.Crr != 0.0035 || tt.DrivetrainEfficiency != 0.98 {
+		t.Errorf("Bike(tt): got: %+v (%v)", tt, err)
+	}
+	if _, err := p.Bike("mtb"); err == nil {
+		t.Errorf("Bike(mtb): got: nil, want: error")
+	}
+
+	if _, err := ReadProfiles(strings.NewReader(`{"riders": {"alice": {"weight": 60}}}`)); err == nil {
+		t.Errorf("ReadProfiles(unknown field): got: nil, want: error")
+	}
+}
+
+func TestRiderProfilePositionCdA(t *testing.T) {
+	p, _ := ReadProfiles(strings.NewReader(profiles))
+
+	tests := []struct {
+		rider, position string
+		expected        float64
+	}{
+		{"alice", "", 0.32},
+		{"alice", "tt", 0.22},
+		{"bob", "", 0.3},
+		{"bob", "drops", 0.3},
+	}
+	for _, tt := range tests {
+		r, err := p.Riders[tt.rider].Rider(tt.position)
+		if err != nil || !Eqf(r.CdA, tt.expected) || r.Mass != p.Riders[tt.rider].Mass {
+			t.Errorf("%s.Rider(%s): got: %+v (%v), want: %.3f", tt.rider, tt.position, r, err, tt.expected)
+		}
+	}
+
+	if _, err := p.Riders["alice"].PositionCdA("tops"); err == nil {
+		t.Errorf("alice.PositionCdA(tops): got: nil, want: error")
+	}
+	two := RiderProfile{CdA: map[string]float64{"hoods": 0.32, "tt": 0.22}}
+	if _, err := two.PositionCdA(""); err == nil {
+		t.Errorf("PositionCdA(): got: nil, want: error")
+	}
+}
+
+func TestBikeProfileBike(t *testing.T) {
+	tests := []struct {
+		profile  BikeProfile
+		expected Bike
+	}{
+		{BikeProfile{Mass: 8.5, Tire: 25, Crr: 0.0035, DrivetrainEfficiency: 0.98},
+			Bike{Mass: 8.5, Crr: 0.0035, TireRadius: R700x25, WheelInertia: I, DrivetrainEfficiency: 0.98, Fw: Fw}},
+		{BikeProfile{Mass: 7.5},
+			Bike{Mass: 7.5, Crr: Crr, TireRadius: R700x23, WheelInertia: I, DrivetrainEfficiency: Ec, Fw: Fw}},
+	}
+	for _, tt := range tests {
+		actual, err := tt.profile.Bike()
+		if err != nil || actual != tt.expected {
+			t.Errorf("%+v.Bike(): got: %+v (%v), want: %+v", tt.profile, actual, err, tt.expected)
+		}
+	}
+
+	if _, err := (BikeProfile{Tire: 24}).Bike(); err == nil {
+		t.Errorf("Bike(tire=24): got: nil, want: error")
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	defer os.Setenv("XDG_CONFIG_HOME", xdg)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+
+	path, err := ProfilesPath()
+	if err != nil || path != filepath.Join(dir, "calc", "profiles.json") {
+		t.Errorf("ProfilesPath(): got: %s (%v)", path, err)
+	}
+	if _, err := LoadProfiles(""); err == nil {
+		t.Errorf("LoadProfiles(missing): got: nil, want: error")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(profiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadProfiles("")
+	if err != nil || len(p.Riders) != 2 || len(p.Bikes) != 2 {
+		t.Errorf("LoadProfiles(): got: %+v (%v)", p, err)
+	}
+}
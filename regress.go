@@ -0,0 +1,230 @@
+package calc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Confidence is the confidence level of the intervals reported by Regress.
+const Confidence = 0.95
+
+// Run is a steady state field test at a constant speed.
+type Run struct {
+	// Power is the net total power in watts.
+	Power float64
+	// Vg is the ground velocity in m/s.
+	Vg float64
+	// Va is the air velocity in m/s.
+	Va float64
+	// Grade is the road gradient (rise/run).
+	Grade float64
+}
+
+// Regression is the result of estimating the CdA and Crr from Runs.
+type Regression struct {
+	Estimate
+	// CdAError is the half-width of the confidence interval of the CdA.
+	CdAError float64
+	// CrrError is the half-width of the confidence interval of the Crr.
+	CrrError float64
+	// Residuals is the difference in watts between the power of each run and
+	// the power predicted by the estimated CdA and Crr.
+	Residuals []float64
+}
+
+// Regress estimates the CdA and Crr of the Model m from the Runs rs using
+// linear least squares regression on the Pat and Prr terms of Pcomp. The
+// RMSE of the Regression is in watts and the errors are the half-widths of the
+// Confidence intervals of each estimate.
+func (m Model) Regress(rs []Run) (Regression, error) {
+	if len(rs) < 3 {
+		return Regression{}, fmt.Errorf("at least 3 runs are required but only %d were provided", len(rs))
+	}
+
+	// P*ec - Pat(fw) - Pwb - Ppe = cda * Pat(cda = 1) + crr * Prr(crr = 1)
+	rho, g, mt, b := m.Environment.Rho, m.Environment.G, m.Mass(), m.Bike
+	x := make([][]float64, len(rs))
+	y := make([]float64, len(rs))
+	for i, r := range rs {
+		x[i] = []float64{Pat(rho, 1, 0, r.Va, r.Vg), Prr(r.Vg, r.Grade, 1, mt, g)}
+		y[i] = r.Power*b.DrivetrainEfficiency - Pat(rho, 0, b.Fw, r.Va, r.Vg) - Pwb(r.Vg) - Ppe(r.Vg, mt, g, r.Grade)
+	}
+
+	beta, cov, err := lstsq(x, y)
+	if err != nil {
+		return Regression{}, err
+	}
+
+	reg := Regression{Estimate: Estimate{CdA: beta[0], Crr: beta[1]}, Residuals: make([]float64, len(rs))}
+	sse := 0.0
+	for i := range rs {
+		e := y[i] - beta[0]*x[i][0] - beta[1]*x[i][1]
+		reg.Residuals[i] = e / b.DrivetrainEfficiency
+		sse += e * e
+	}
+	reg.RMSE = math.Sqrt(sse/float64(len(rs))) / b.DrivetrainEfficiency
+
+	df := float64(len(rs) - 2)
+	s2 := sse / df
+	t := studentT(1-(1-Confidence)/2, df)
+	reg.CdAError = t * math.Sqrt(s2*cov[0][0])
+	reg.CrrError = t * math.Sqrt(s2*cov[1][1])
+	return reg, nil
+}
+
+// ReadRuns parses Runs from CSV data read from r. If the first row is a header,
+// the columns are identified by name ('p', 'vg', 'va' and 'gr'), otherwise the
+// columns are expected in that order. If the air velocity is missing it is
+// assumed to be equal to the ground velocity, and the grade is assumed to be 0.
+func ReadRuns(r io.Reader) ([]Run, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	cols := map[string]int{"p": 0, "vg": 1, "va": 2, "gr": 3}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(rows[0][0]), 64); err != nil {
+		cols = make(map[string]int)
+		for i, name := range rows[0] {
+			cols[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		rows = rows[1:]
+	}
+	if _, ok := cols["p"]; !ok {
+		return nil, fmt.Errorf("missing 'p' column")
+	}
+	if _, ok := cols["vg"]; !ok {
+		return nil, fmt.Errorf("missing 'vg' column")
+	}
+
+	var runs []Run
+	for i, row := range rows {
+		field := func(name string, def float64) (float64, error) {
+			c, ok := cols[name]
+			if !ok || c >= len(row) || strings.TrimSpace(row[c]) == "" {
+				return def, nil
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(row[c]), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s '%s' on row %d", name, row[c], i+1)
+			}
+			return v, nil
+		}
+
+		var run Run
+		if run.Power, err = field("p", math.NaN()); err != nil {
+			return nil, err
+		}
+		if run.Vg, err = field("vg", math.NaN()); err != nil {
+			return nil, err
+		}
+		if math.IsNaN(run.Power) || math.IsNaN(run.Vg) {
+			return nil, fmt.Errorf("missing p or vg on row %d", i+1)
+		}
+		if run.Va, err = field("va", run.Vg); err != nil {
+			return nil, err
+		}
+		if run.Grade, err = field("gr", 0); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// studentT returns the p-th quantile of Student's t-distribution with df
+// degrees of freedom.
+func studentT(p, df float64) float64 {
+	// cdf is the cumulative distribution function for t >= 0
+	cdf := func(t float64) float64 {
+		return 1 - 0.5*betai(df/2, 0.5, df/(df+t*t))
+	}
+
+	lo, hi := 0.0, 1.0
+	for cdf(hi) < p {
+		lo, hi = hi, hi*2
+	}
+	for j := 0; j < 100; j++ {
+		mid := (lo + hi) / 2
+		if cdf(mid) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// betai returns the regularized incomplete beta function I_x(a, b).
+func betai(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a + b)
+	lb, _ := math.Lgamma(a)
+	lc, _ := math.Lgamma(b)
+	front := math.Exp(la - lb - lc + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betacf(a, b, x) / a
+	}
+	return 1 - front*betacf(b, a, 1-x)/b
+}
+
+// betacf evaluates the continued fraction for the incomplete beta function
+// using the modified Lentz's method.
+func betacf(a, b, x float64) float64 {
+	// tiny is a small number to prevent division by zero
+	const tiny = 1e-300
+	// epsilon is the relative precision of the result
+	const epsilon = 1e-15
+
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m <= 300; m++ {
+		m2 := 2 * m
+		aa := m * (b - m) * x / ((a + m2 - 1) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		aa = -(a + m) * (a + b + m) * x / ((a + m2) * (a + m2 + 1))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,85 @@
+package calc
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegress(t *testing.T) {
+	actual := testModel(67, 8, 0.27, 0.0045, Rho0, 0, 0)
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+
+	var runs []Run
+	for i, vg := range []float64{6, 8, 10, 12, 14} {
+		for j, wind := range []float64{-1, 0, 1.5} {
+			c := Conditions{Grade: 0.002 * float64(j)}
+			actual.Environment.Wind = Wind{Speed: wind}
+			// +/- 1 W of deterministic noise
+			noise := math.Sin(float64(3*i + j))
+			runs = append(runs, Run{Power: actual.Power(vg, c) + noise, Vg: vg, Va: actual.Va(vg, c), Grade: c.Grade})
+		}
+	}
+
+	reg, err := m.Regress(runs)
+	if err != nil {
+		t.Fatalf("Regress: got: %v", err)
+	}
+	if !Eqf(reg.CdA, 0.27, 5e-3) || !Eqf(reg.Crr, 0.0045, 2e-2) {
+		t.Errorf("Regress: got: %+v, want: {CdA:0.27 Crr:0.0045}", reg.Estimate)
+	}
+	if math.Abs(reg.CdA-0.27) > reg.CdAError || math.Abs(reg.Crr-0.0045) > reg.CrrError {
+		t.Errorf("Regress: got: %.4f ± %.4f, %.5f ± %.5f, want: intervals containing 0.27 and 0.0045",
+			reg.CdA, reg.CdAError, reg.Crr, reg.CrrError)
+	}
+	if len(reg.Residuals) != len(runs) || reg.RMSE > 1 {
+		t.Errorf("Regress: got: %d residuals with RMSE %.3f, want: %d residuals with RMSE < 1",
+			len(reg.Residuals), reg.RMSE, len(runs))
+	}
+}
+
+func TestReadRuns(t *testing.T) {
+	tests := []struct {
+		csv      string
+		expected []Run
+	}{
+		{"p,vg,va,gr\n200,10,11,0.01\n250,11,,\n", []Run{{200, 10, 11, 0.01}, {250, 11, 11, 0}}},
+		{"vg, P\n10, 200\n", []Run{{200, 10, 10, 0}}},
+		{"200,10\n300,12,10\n", []Run{{200, 10, 10, 0}, {300, 12, 10, 0}}},
+	}
+	for _, tt := range tests {
+		actual, err := ReadRuns(strings.NewReader(tt.csv))
+		if err != nil || len(actual) != len(tt.expected) {
+			t.Errorf("ReadRuns(%q): got: %v (%v), want: %v", tt.csv, actual, err, tt.expected)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != tt.expected[i] {
+				t.Errorf("ReadRuns(%q): got: %v, want: %v", tt.csv, actual, tt.expected)
+				break
+			}
+		}
+	}
+
+	for _, csv := range []string{"vg,va\n10,10\n", "p,vg\n200,x\n"} {
+		if _, err := ReadRuns(strings.NewReader(csv)); err == nil {
+			t.Errorf("ReadRuns(%q): expected error", csv)
+		}
+	}
+}
+
+func TestStudentT(t *testing.T) {
+	tests := []struct {
+		p, df, expected float64
+	}{
+		{0.975, 1, 12.706},
+		{0.975, 10, 2.228},
+		{0.95, 30, 1.697},
+	}
+	for _, tt := range tests {
+		actual := studentT(tt.p, tt.df)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("studentT(%.3f, %.3f): got: %.3f, want: %.3f", tt.p, tt.df, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package calc
+
+import (
+	"math"
+)
+
+// steps are the step sizes used to numerically differentiate with respect to
+// each Variable.
+var steps = []float64{
+	VarCdA:        1e-4,
+	VarCrr:        1e-5,
+	VarMass:       1e-2,
+	VarGrade:      1e-5,
+	VarWind:       1e-3,
+	VarRho:        1e-4,
+	VarEfficiency: 1e-4,
+}
+
+// Partial is the sensitivity of a performance to a particular Variable.
+type Partial struct {
+	// Variable is the variable being varied.
+	Variable Variable
+	// Value is the value of the variable at the operating point.
+	Value float64
+	// Time is the partial derivative of the time with respect to the variable
+	// at constant power, in seconds per unit.
+	Time float64
+	// Power is the partial derivative of the power with respect to the
+	// variable at constant time, in watts per unit.
+	Power float64
+}
+
+// Sensitivity describes how a performance changes in response to small changes
+// in the model's inputs.
+type Sensitivity struct {
+	// Result is the performance at the operating point.
+	Result
+	// Time is the partial derivative of the time with respect to the power,
+	// in seconds per watt.
+	Time float64
+	// Partials are the sensitivities to each Variable.
+	Partials []Partial
+}
+
+// Sensitivity calculates the partial derivatives of the time taken by Model m
+// to ride the course at a constant net total power of p watts, and of the power
+// required to ride the course in that time, with respect to the power and each
+// Variable. If the Model does not specify an air density (Rho is 0), the air
+// density of every segment is scaled to vary VarRho, whose Value is the mean
+// air density of the segments weighted by their length.
+func (c Course) Sensitivity(m Model, p float64) (Sensitivity, error) {
+	// dp is the step size in watts used to differentiate with respect to power
+	const dp = 1e-2
+
+	r, err := c.Time(m, p)
+	if err != nil {
+		return Sensitivity{}, err
+	}
+	s := Sensitivity{Result: r}
+
+	lo, err := c.Time(m, p-dp)
+	if err != nil {
+		return Sensitivity{}, err
+	}
+	hi, err := c.Time(m, p+dp)
+	if err != nil {
+		return Sensitivity{}, err
+	}
+	s.Time = (hi.Time - lo.Time) / (2 * dp)
+
+	for v := range variables {
+		v := Variable(v)
+		x, h := v.Get(m, c), steps[v]
+		time := func(x float64) (float64, error) {
+			mx, cx := v.Set(m, c, x)
+			r, err := cx.Time(mx, p)
+			return r.Time, err
+		}
+		if v == VarRho && m.Environment.Rho == 0 {
+			// the air density of each segment is derived from its elevation,
+			// so the densities are scaled together relative to their mean
+			x = c.density(m)
+			time = func(y float64) (float64, error) {
+				return c.scaled(m, p, y/x)
+			}
+		}
+
+		lo, err := time(x - h)
+		if err != nil {
+			return Sensitivity{}, err
+		}
+		hi, err := time(x + h)
+		if err != nil {
+			return Sensitivity{}, err
+		}
+
+		// by the implicit function theorem, ∂p/∂x at constant t = -(∂t/∂x)/(∂t/∂p)
+		dt := (hi - lo) / (2 * h)
+		s.Partials = append(s.Partials, Partial{Variable: v, Value: x, Time: dt, Power: -dt / s.Time})
+	}
+	return s, nil
+}
+
+// Partial returns the sensitivity to the Variable v, or a Partial with NaN
+// derivatives if it is unknown.
+func (s Sensitivity) Partial(v Variable) Partial {
+	for _, p := range s.Partials {
+		if p.Variable == v {
+			return p
+		}
+	}
+	return Partial{Variable: v, Value: math.NaN(), Time: math.NaN(), Power: math.NaN()}
+}
+
+// density returns the mean air density of the segments of the course ridden by
+// Model m, weighted by their length.
+func (c Course) density(m Model) float64 {
+	rho, d := 0.0, 0.0
+	for _, s := range c {
+		rho += m.segment(s).Environment.Rho * s.Length
+		d += s.Length
+	}
+	if d == 0 {
+		return m.segment(Segment{Elevation: c.Elevation()}).Environment.Rho
+	}
+	return rho / d
+}
+
+// scaled returns the time in seconds taken by Model m to ride the course at a
+// constant net total power of p watts with the air density of every segment
+// scaled by k.
+func (c Course) scaled(m Model, p, k float64) (float64, error) {
+	t := 0.0
+	for _, s := range c {
+		ms := m.segment(s)
+		ms.Environment.Rho *= k
+		r, err := Course{s}.Time(ms, p)
+		if err != nil {
+			return 0, err
+		}
+		t += r.Time
+	}
+	return t, nil
+}
@@ -0,0 +1,93 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCourseSensitivity(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 3, 0)
+	p := 300.0
+	courses := []Course{
+		{{Length: 40000}},
+		{{Length: 6000, Grade: 0.03, Heading: 45}, {Length: 4000, Grade: -0.01, Heading: 225}},
+	}
+	for _, c := range courses {
+		s, err := c.Sensitivity(m, p)
+		if err != nil {
+			t.Fatalf("%v.Sensitivity(%+v, %.3f): got: %v", c, m, p, err)
+		}
+		if len(s.Partials) != len(variables) {
+			t.Errorf("%v.Sensitivity(%+v, %.3f): got: %d partials, want: %d", c, m, p, len(s.Partials), len(variables))
+		}
+
+		// compare against larger finite differences
+		lo, _ := c.Time(m, p-1)
+		hi, _ := c.Time(m, p+1)
+		if expected := (hi.Time - lo.Time) / 2; !Eqf(s.Time, expected) {
+			t.Errorf("%v.Sensitivity(%+v, %.3f).Time: got: %.5f, want: %.5f", c, m, p, s.Time, expected)
+		}
+		for _, v := range []Variable{VarCdA, VarMass, VarWind} {
+			h := 100 * steps[v]
+			actual := s.Partial(v)
+			ml, cl := v.Set(m, c, actual.Value-h)
+			mh, ch := v.Set(m, c, actual.Value+h)
+			lo, _ := cl.Time(ml, p)
+			hi, _ := ch.Time(mh, p)
+			if expected := (hi.Time - lo.Time) / (2 * h); !Eqf(actual.Time, expected) {
+				t.Errorf("%v.Sensitivity(%+v, %.3f).Partial(%s).Time: got: %.5f, want: %.5f",
+					c, m, p, v, actual.Time, expected)
+			}
+			pl, _ := cl.Power(ml, s.Result.Time)
+			ph, _ := ch.Power(mh, s.Result.Time)
+			if expected := (ph.Power - pl.Power) / (2 * h); !Eqf(actual.Power, expected) {
+				t.Errorf("%v.Sensitivity(%+v, %.3f).Partial(%s).Power: got: %.5f, want: %.5f",
+					c, m, p, v, actual.Power, expected)
+			}
+		}
+	}
+}
+
+func TestCourseSensitivityElevation(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, 0, 0, 0)
+	c := Course{{Length: 10000, Elevation: 1500}}
+	s, err := c.Sensitivity(m, 250)
+	if err != nil {
+		t.Fatalf("%v.Sensitivity(%+v, 250): got: %v", c, m, err)
+	}
+	if len(s.Partials) != len(variables) {
+		t.Errorf("%v.Sensitivity(%+v, 250): got: %d partials, want: %d", c, m, len(s.Partials), len(variables))
+	}
+	if actual := s.Partial(VarCdA); actual.Time <= 0 || actual.Power <= 0 {
+		t.Errorf("%v.Sensitivity(%+v, 250).Partial(%s): got: %+v, want: positive", c, m, VarCdA, actual)
+	}
+	if actual := s.Partial(Variable(-1)); !math.IsNaN(actual.Time) {
+		t.Errorf("%v.Sensitivity(%+v, 250).Partial(%s): got: %+v, want: NaN", c, m, Variable(-1), actual)
+	}
+
+	// a single segment is equivalent to specifying its air density
+	mr := m
+	mr.Environment.Rho = Rho(1500, G)
+	sr, err := c.Sensitivity(mr, 250)
+	if err != nil {
+		t.Fatalf("%v.Sensitivity(%+v, 250): got: %v", c, mr, err)
+	}
+	actual, expected := s.Partial(VarRho), sr.Partial(VarRho)
+	if !Eqf(actual.Value, expected.Value) || !Eqf(actual.Time, expected.Time) || !Eqf(actual.Power, expected.Power) {
+		t.Errorf("%v.Sensitivity(%+v, 250).Partial(%s): got: %+v, want: %+v", c, m, VarRho, actual, expected)
+	}
+
+	// otherwise the value is the mean air density weighted by length
+	c = Course{{Length: 3000, Elevation: 500}, {Length: 1000, Grade: 0.05, Elevation: 2500}}
+	s, err = c.Sensitivity(m, 250)
+	if err != nil {
+		t.Fatalf("%v.Sensitivity(%+v, 250): got: %v", c, m, err)
+	}
+	actual = s.Partial(VarRho)
+	if expected := (3*Rho(500, G) + Rho(2500, G)) / 4; !Eqf(actual.Value, expected) {
+		t.Errorf("%v.Sensitivity(%+v, 250).Partial(%s).Value: got: %.5f, want: %.5f", c, m, VarRho, actual.Value, expected)
+	}
+	if actual.Time <= 0 || actual.Power <= 0 {
+		t.Errorf("%v.Sensitivity(%+v, 250).Partial(%s): got: %+v, want: positive", c, m, VarRho, actual)
+	}
+}
@@ -0,0 +1,204 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxDuration is the maximum number of seconds a Simulation without a Duration
+// will run for before giving up on reaching its Distance.
+const maxDuration = 7 * 24 * 60 * 60
+
+// minSpeed is the ground velocity in m/s used to calculate the forces acting on
+// a bicycle which is (nearly) stationary.
+const minSpeed = 0.01
+
+// bisections is the number of bisections used to find the equilibrium kinetic
+// energy of the bicycle when a step overshoots it.
+const bisections = 50
+
+// ErrStalled is returned when a Simulation is unable to cover its Distance, i.e.
+// the bicycle has come to rest and the power produced is unable to move it.
+var ErrStalled = errors.New("simulation stalled before reaching the distance")
+
+// Integrator is the numerical method used to integrate the equation of motion.
+type Integrator int
+
+const (
+	// Euler is the first order (forward) Euler method.
+	Euler Integrator = iota
+	// RK4 is the classic fourth order Runge-Kutta method.
+	RK4
+)
+
+// Point is the state of a simulated performance at a particular time.
+type Point struct {
+	// Time is the elapsed time in seconds.
+	Time float64
+	// Distance is the distance travelled in metres.
+	Distance float64
+	// Speed is the ground velocity of the bicycle in m/s.
+	Speed float64
+	// Power is the net total power being produced in watts.
+	Power float64
+	// Components is the breakdown of Power, where KE is the power going
+	// towards (or, if negative, coming from) the kinetic energy of the bicycle.
+	Components
+}
+
+// Simulation describes a performance to be simulated by Model.Simulate.
+type Simulation struct {
+	// Power returns the net total power in watts produced at time t, distance
+	// d and ground velocity vg.
+	Power func(t, d, vg float64) float64
+	// Conditions returns the Conditions of the road at distance d. If nil, the
+	// road is assumed to be flat with a heading of 0.
+	Conditions func(d float64) Conditions
+	// Step is the duration of each time step in seconds.
+	Step float64
+	// Integrator is the numerical method to use.
+	Integrator Integrator
+	// Speed is the initial ground velocity of the bicycle in m/s.
+	Speed float64
+	// Duration is the time in seconds after which the simulation will stop.
+	Duration float64
+	// Distance is the distance in metres after which the simulation will stop.
+	Distance float64
+}
+
+// ConstantPower returns a function suitable for use as Simulation.Power which
+// always produces p watts.
+func ConstantPower(p float64) func(t, d, vg float64) float64 {
+	return func(t, d, vg float64) float64 {
+		return p
+	}
+}
+
+// Simulate integrates the equation of motion of the Model over time for the
+// Simulation s, returning the state of the performance at every step until
+// either the Duration has elapsed or the Distance has been covered. Unlike
+// Speed, changes in kinetic energy (including that of the rotating wheels) are
+// accounted for, allowing for accelerations and decelerations to be modelled.
+func (m Model) Simulate(s Simulation) ([]Point, error) {
+	if s.Power == nil {
+		return nil, fmt.Errorf("simulation requires a power function")
+	}
+	if s.Step <= 0 {
+		return nil, fmt.Errorf("step must be positive but was %f", s.Step)
+	}
+	if s.Duration <= 0 && s.Distance <= 0 {
+		return nil, fmt.Errorf("simulation requires a positive duration or distance")
+	}
+	if s.Speed < 0 {
+		return nil, fmt.Errorf("speed must be non negative but was %f", s.Speed)
+	}
+	if s.Conditions == nil {
+		s.Conditions = func(d float64) Conditions { return Conditions{} }
+	}
+
+	// The equation of motion is integrated in terms of the kinetic energy e of
+	// the system instead of velocity to avoid the singularity at vg = 0 and
+	// allow for standing starts.
+	me := m.Mass()
+	if m.Bike.TireRadius > 0 {
+		me += m.Bike.WheelInertia / math.Pow(m.Bike.TireRadius, 2)
+	}
+	ec := m.Bike.DrivetrainEfficiency
+	speed := func(e float64) float64 {
+		return math.Sqrt(2 * math.Max(e, 0) / me)
+	}
+	deriv := func(t, d, e float64) (float64, float64) {
+		vg := speed(e)
+		// at rest no power is required but forces still act on the bicycle
+		pr := m.Power(math.Max(vg, minSpeed), s.Conditions(d))
+		return vg, ec * (s.Power(t, d, vg) - pr)
+	}
+	integrate := func(t, d, e, h float64) (float64, float64) {
+		switch s.Integrator {
+		case RK4:
+			d1, e1 := deriv(t, d, e)
+			d2, e2 := deriv(t+h/2, d+h/2*d1, e+h/2*e1)
+			d3, e3 := deriv(t+h/2, d+h/2*d2, e+h/2*e2)
+			d4, e4 := deriv(t+h, d+h*d3, e+h*e3)
+			return d + h/6*(d1+2*d2+2*d3+d4), e + h/6*(e1+2*e2+2*e3+e4)
+		default:
+			dd, de := deriv(t, d, e)
+			return d + h*dd, e + h*de
+		}
+	}
+	step := func(t, d, e, h float64) (float64, float64) {
+		_, de := deriv(t, d, e)
+		dn, en := integrate(t, d, e, h)
+		// a step from rest can be unstable, in which case an Euler step is
+		// taken instead
+		if de > 0 && en <= e {
+			var dd float64
+			dd, de = deriv(t, d, e)
+			dn, en = d+h*dd, e+h*de
+		}
+		en = math.Max(en, 0)
+		// The kinetic energy can't pass through the equilibrium, where the
+		// power produced matches the power required, but when the velocity is
+		// low and changes rapidly a step may overshoot it, after which the
+		// velocity would swing back and forth without settling, so the step
+		// is limited to the equilibrium.
+		if _, den := deriv(t+h, dn, en); (de > 0 && den < 0) || (de < 0 && den > 0) {
+			lo, hi := e, en
+			for i := 0; i < bisections; i++ {
+				mid := (lo + hi) / 2
+				if _, dm := deriv(t+h, dn, mid); (dm > 0) == (de > 0) {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			en = lo
+		}
+		// the bicycle stops rather than rolling backwards
+		return dn, en
+	}
+	point := func(t, d, e float64) Point {
+		vg := speed(e)
+		p := s.Power(t, d, vg)
+		comp := m.Components(vg, s.Conditions(d))
+		comp.KE = p - (comp.AT + comp.RR + comp.WB + comp.PE)
+		return Point{Time: t, Distance: d, Speed: vg, Power: p, Components: comp}
+	}
+
+	t, d, e := 0.0, 0.0, 0.5*me*s.Speed*s.Speed
+	points := []Point{point(t, d, e)}
+	for {
+		if s.Duration > 0 && t >= s.Duration {
+			return points, nil
+		}
+		if s.Duration <= 0 && t >= maxDuration {
+			return points, ErrStalled
+		}
+
+		h := s.Step
+		if s.Duration > 0 && t+h > s.Duration {
+			h = s.Duration - t
+		}
+		dn, en := step(t, d, e, h)
+		// redo the final step with a shorter duration to land on the distance
+		if s.Distance > 0 && dn > s.Distance {
+			h *= (s.Distance - d) / (dn - d)
+			dn, en = step(t, d, e, h)
+			dn = s.Distance
+		}
+
+		// Without a Duration the simulation only ends once the Distance has
+		// been covered, so if the bicycle has come to rest and is unable to
+		// move forward it has stalled.
+		stalled := s.Duration <= 0 && en == 0 && dn <= d
+		t, d, e = t+h, dn, en
+		points = append(points, point(t, d, e))
+		if s.Distance > 0 && d >= s.Distance {
+			return points, nil
+		}
+		if stalled {
+			return points, ErrStalled
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	tests := []struct {
+		p, gr, vg0, step float64
+		integrator       Integrator
+	}{
+		{250, 0, 0, 0.1, Euler},
+		{250, 0, 0, 1, RK4},
+		{300, 0.05, 15, 1, RK4},
+		{0, -0.06, 0, 1, RK4},
+		// the power is barely enough to move the bicycle
+		{5, 0.2, 0, 1, Euler},
+		{5, 0.2, 0, 1, RK4},
+	}
+	for _, tt := range tests {
+		c := Conditions{Grade: tt.gr}
+		expected, _ := m.Speed(tt.p, c)
+		points, err := m.Simulate(Simulation{
+			Power:      ConstantPower(tt.p),
+			Conditions: func(d float64) Conditions { return c },
+			Step:       tt.step,
+			Integrator: tt.integrator,
+			Speed:      tt.vg0,
+			Duration:   600,
+		})
+		if err != nil {
+			t.Errorf("Simulate(%.3f, %.3f, %.3f, %.3f, %d): got: %v", tt.p, tt.gr, tt.vg0, tt.step, tt.integrator, err)
+			continue
+		}
+		actual := points[len(points)-1]
+		if !Eqf(actual.Speed, expected) || !Eqf(actual.Time, 600) {
+			t.Errorf("Simulate(%.3f, %.3f, %.3f, %.3f, %d): got: %.3f m/s @ %.3f s, want: %.3f m/s @ 600 s",
+				tt.p, tt.gr, tt.vg0, tt.step, tt.integrator, actual.Speed, actual.Time, expected)
+		}
+	}
+}
+
+func TestSimulateNearRest(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Conditions{Grade: 0.2}
+	vg, _ := m.Speed(5, c)
+	expected := 100 / vg
+	for _, step := range []float64{0.1, 1, 10} {
+		for _, integrator := range []Integrator{Euler, RK4} {
+			points, err := m.Simulate(Simulation{
+				Power:      ConstantPower(5),
+				Conditions: func(d float64) Conditions { return c },
+				Step:       step,
+				Integrator: integrator,
+				Distance:   100,
+			})
+			if err != nil {
+				t.Errorf("Simulate(%.3f, %d): got: %v", step, integrator, err)
+				continue
+			}
+			if actual := points[len(points)-1].Time; !Eqf(actual, expected, 1e-2) {
+				t.Errorf("Simulate(%.3f, %d): got: %.3f s, want: %.3f s", step, integrator, actual, expected)
+			}
+		}
+	}
+}
+
+func TestSimulateDistance(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	tests := []struct {
+		p, d, step float64
+		integrator Integrator
+		expected   float64
+	}{
+		{400, 200, 0.01, Euler, 23.15},
+		{400, 200, 1, RK4, 23.15},
+	}
+	for _, tt := range tests {
+		points, err := m.Simulate(Simulation{
+			Power:      ConstantPower(tt.p),
+			Step:       tt.step,
+			Integrator: tt.integrator,
+			Distance:   tt.d,
+		})
+		if err != nil {
+			t.Errorf("Simulate(%.3f, %.3f, %.3f, %d): got: %v", tt.p, tt.d, tt.step, tt.integrator, err)
+			continue
+		}
+		actual := points[len(points)-1]
+		if !Eqf(actual.Time, tt.expected) || actual.Distance != tt.d {
+			t.Errorf("Simulate(%.3f, %.3f, %.3f, %d): got: %.3f s @ %.3f m, want: %.3f s @ %.3f m",
+				tt.p, tt.d, tt.step, tt.integrator, actual.Time, actual.Distance, tt.expected, tt.d)
+		}
+		ke := 0.0
+		for _, p := range points {
+			ke += p.KE
+		}
+		if ke <= 0 {
+			t.Errorf("Simulate(%.3f, %.3f, %.3f, %d): expected power to be spent on acceleration",
+				tt.p, tt.d, tt.step, tt.integrator)
+		}
+	}
+}
+
+func TestSimulateStalled(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	tests := []struct {
+		p, gr, vg0, step float64
+		integrator       Integrator
+	}{
+		{0, 0.05, 5, 60, Euler},
+		{0, 0.05, 5, 1, RK4},
+		{0, 0.2, 0, 0.1, Euler},
+	}
+	for _, tt := range tests {
+		points, err := m.Simulate(Simulation{
+			Power:      ConstantPower(tt.p),
+			Conditions: func(d float64) Conditions { return Conditions{Grade: tt.gr} },
+			Step:       tt.step,
+			Integrator: tt.integrator,
+			Speed:      tt.vg0,
+			Distance:   1000,
+		})
+		if err != ErrStalled {
+			t.Errorf("Simulate(%.3f, %.3f, %.3f): got: %v, want: %v", tt.p, tt.gr, tt.vg0, err, ErrStalled)
+			continue
+		}
+		// the stall must be detected as soon as the bicycle comes to rest
+		if last := points[len(points)-1]; last.Speed != 0 || len(points) > 100 {
+			t.Errorf("Simulate(%.3f, %.3f, %.3f): got: %.3f m/s after %d steps, want: stalled",
+				tt.p, tt.gr, tt.vg0, last.Speed, len(points))
+		}
+	}
+}
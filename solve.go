@@ -0,0 +1,218 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Variable is a parameter of the model which can be solved for by Course.Solve.
+type Variable int
+
+const (
+	// VarCdA is the CdA of the Rider.
+	VarCdA Variable = iota
+	// VarCrr is the Crr of the Bike.
+	VarCrr
+	// VarMass is the total mass of the Rider and Bike, where the Bike's mass
+	// is held constant.
+	VarMass
+	// VarGrade is the average grade of the Course, where the grade of every
+	// segment is shifted by the same amount.
+	VarGrade
+	// VarWind is the speed of the Wind, where the direction is held constant
+	// and a negative speed is a wind from the opposite direction.
+	VarWind
+	// VarRho is the air density of the Environment.
+	VarRho
+	// VarEfficiency is the drivetrain efficiency of the Bike.
+	VarEfficiency
+)
+
+// variables are the name and range of values searched for each Variable.
+var variables = []struct {
+	name   string
+	lo, hi float64
+}{
+	VarCdA:        {"cda", 0, 2},
+	VarCrr:        {"crr", 0, 0.1},
+	VarMass:       {"mt", 1, 500},
+	VarGrade:      {"gr", -0.5, 0.5},
+	VarWind:       {"vw", -50, 50},
+	VarRho:        {"rho", 0.01, 5},
+	VarEfficiency: {"ec", 0.01, 1},
+}
+
+// ParseVariable returns the Variable named s ('cda', 'crr', 'mt', 'gr', 'vw',
+// 'rho' or 'ec').
+func ParseVariable(s string) (Variable, error) {
+	for v, d := range variables {
+		if strings.ToLower(s) == d.name {
+			return Variable(v), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid variable '%s'", s)
+}
+
+// String returns the name of the Variable.
+func (v Variable) String() string {
+	if v < 0 || int(v) >= len(variables) {
+		return fmt.Sprintf("Variable(%d)", int(v))
+	}
+	return variables[v].name
+}
+
+// Get returns the value of the Variable for Model m riding the Course c.
+func (v Variable) Get(m Model, c Course) float64 {
+	switch v {
+	case VarCdA:
+		if m.Rider.Curve != nil {
+			return m.Rider.Curve.CdA(0)
+		}
+		return m.Rider.CdA
+	case VarCrr:
+		return m.Bike.Crr
+	case VarMass:
+		return m.Mass()
+	case VarGrade:
+		return c.Grade()
+	case VarWind:
+		return m.Environment.Wind.Speed
+	case VarRho:
+		return m.Environment.Rho
+	case VarEfficiency:
+		return m.Bike.DrivetrainEfficiency
+	default:
+		return math.NaN()
+	}
+}
+
+// Set returns copies of the Model m and Course c with the Variable set to x.
+func (v Variable) Set(m Model, c Course, x float64) (Model, Course) {
+	switch v {
+	case VarCdA:
+		m.Rider.CdA = x
+		if m.Rider.Curve != nil {
+			m.Rider.Curve = ScaleCdA(m.Rider.Curve, x)
+		}
+	case VarCrr:
+		m.Bike.Crr = x
+	case VarMass:
+		m.Rider.Mass = x - m.Bike.Mass
+	case VarGrade:
+		shift := x - c.Grade()
+		c = append(Course(nil), c...)
+		for i := range c {
+			c[i].Grade += shift
+		}
+	case VarWind:
+		m.Environment.Wind.Speed = x
+	case VarRho:
+		m.Environment.Rho = x
+	case VarEfficiency:
+		m.Bike.DrivetrainEfficiency = x
+	}
+	return m, c
+}
+
+// Solve finds the value of the Variable v which results in Model m completing
+// the course in t seconds while producing a constant net total power of p
+// watts, e.g. the CdA required to complete a time trial in a given time. The
+// target out determines what is matched: OutputTime matches the time taken at p
+// watts to t, while OutputPower matches the power required to complete the
+// course in t seconds to p. The range of plausible values of v is scanned for a
+// bracket containing the solution, which is then refined by bisection. If more
+// than one solution exists, the one closest to the current value of v is
+// returned, e.g. a light tailwind rather than the implausibly strong one which,
+// as the drag depends on the square of the air velocity, slows the bicycle just
+// as much. An error describing the range of times or powers possible is
+// returned if no solution can be bracketed.
+func (c Course) Solve(m Model, v Variable, p, t float64, out Output) (float64, error) {
+	// samples is the number of values scanned for a bracket
+	const samples = 200
+	// epsilon is the absolute precision of the result relative to the range
+	const epsilon = 1e-12
+
+	if v < 0 || int(v) >= len(variables) {
+		return 0, fmt.Errorf("invalid variable %d", int(v))
+	}
+	if t <= 0 {
+		return 0, fmt.Errorf("t must be positive but was %f", t)
+	}
+	if len(c) == 0 {
+		return 0, fmt.Errorf("course has no segments")
+	}
+
+	// f is the difference between the output and its target, where the
+	// course being impossible to complete is equivalent to taking forever and
+	// completing it faster than t while coasting to requiring negative power
+	var f func(x float64) float64
+	switch out {
+	case OutputTime:
+		f = func(x float64) float64 {
+			mx, cx := v.Set(m, c, x)
+			r, err := cx.Time(mx, p)
+			if err != nil {
+				return math.Inf(1)
+			}
+			return r.Time - t
+		}
+	case OutputPower:
+		f = func(x float64) float64 {
+			mx, cx := v.Set(m, c, x)
+			r, err := cx.Power(mx, t)
+			if err == nil {
+				return r.Power - p
+			}
+			if r, err := cx.Time(mx, 0); err == nil && r.Time < t {
+				return math.Inf(-1)
+			}
+			return math.Inf(1)
+		}
+	default:
+		return 0, fmt.Errorf("invalid output %d", int(out))
+	}
+
+	// every bracket is found so that the solution closest to the current value
+	// can be refined
+	lo, hi := variables[v].lo, variables[v].hi
+	x0 := v.Get(m, c)
+	step := (hi - lo) / samples
+	min, max := math.Inf(1), math.Inf(-1)
+	bl, bh, found := 0.0, 0.0, false
+	xl, fl := lo, f(lo)
+	for i := 1; i <= samples; i++ {
+		xh := lo + float64(i)*step
+		fh := f(xh)
+		min, max = math.Min(min, math.Min(fl, fh)), math.Max(max, math.Max(fl, fh))
+		if (fl == 0 || (fl < 0) != (fh < 0)) && (!found || math.Abs((xl+xh)/2-x0) < math.Abs((bl+bh)/2-x0)) {
+			bl, bh, found = xl, xh, true
+		}
+		xl, fl = xh, fh
+	}
+
+	if found {
+		xl, xh := bl, bh
+		fl := f(xl)
+		if fl == 0 {
+			return xl, nil
+		}
+		for xh-xl > epsilon*(hi-lo) {
+			xm := (xl + xh) / 2
+			fm := f(xm)
+			if (fm < 0) == (fl < 0) {
+				xl, fl = xm, fm
+			} else {
+				xh = xm
+			}
+		}
+		return (xl + xh) / 2, nil
+	}
+
+	if out == OutputPower {
+		return 0, fmt.Errorf("no value of %s in [%g, %g] results in a power of %.2f W for %.2f s (powers range from %.2f W to %.2f W)",
+			v, lo, hi, p, t, min+p, max+p)
+	}
+	return 0, fmt.Errorf("no value of %s in [%g, %g] results in a time of %.2f s at %.2f W (times range from %.2f s to %.2f s)",
+		v, lo, hi, t, p, min+t, max+t)
+}
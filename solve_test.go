@@ -0,0 +1,76 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestCourseSolve(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 3, 0)
+	c := Course{{Length: 6000, Grade: 0.03, Heading: 45}, {Length: 4000, Grade: 0.01, Heading: 90}}
+	p := 300.0
+
+	tests := []struct {
+		v        Variable
+		expected float64
+	}{
+		{VarCdA, 0.27},
+		{VarCrr, 0.006},
+		{VarMass, 80},
+		{VarGrade, 0.04},
+		{VarWind, 5},
+		{VarWind, -4},
+		{VarRho, 1.1},
+		{VarEfficiency, 0.95},
+	}
+	for _, tt := range tests {
+		mx, cx := tt.v.Set(m, c, tt.expected)
+		if actual := tt.v.Get(mx, cx); !Eqf(actual, tt.expected) {
+			t.Errorf("%s.Get(%s.Set(%.3f)): got: %.3f", tt.v, tt.v, tt.expected, actual)
+		}
+		r, err := cx.Time(mx, p)
+		if err != nil {
+			t.Fatalf("%v.Time(%+v, %.3f): got: %v", cx, mx, p, err)
+		}
+		for _, out := range []Output{OutputTime, OutputPower} {
+			actual, err := c.Solve(m, tt.v, p, r.Time, out)
+			if err != nil || !Eqf(actual, tt.expected) {
+				t.Errorf("%v.Solve(%+v, %s, %.3f, %.3f, %d): got: %.5f (%v), want: %.5f",
+					c, m, tt.v, p, r.Time, out, actual, err, tt.expected)
+			}
+		}
+	}
+}
+
+func TestCourseSolveNoSolution(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Course{{Length: 10000}}
+	tests := []struct {
+		v    Variable
+		p, t float64
+		out  Output
+	}{
+		{VarCdA, 300, 60, OutputTime},
+		{VarCrr, 300, 60, OutputTime},
+		{VarCdA, 300, 60, OutputPower},
+		{VarCdA, 300, 0, OutputTime},
+		{VarCdA, 300, 600, OutputSpeed},
+		{Variable(-1), 300, 600, OutputTime},
+	}
+	for _, tt := range tests {
+		if actual, err := c.Solve(m, tt.v, tt.p, tt.t, tt.out); err == nil {
+			t.Errorf("%v.Solve(%+v, %s, %.3f, %.3f, %d): got: %.5f, want: error", c, m, tt.v, tt.p, tt.t, tt.out, actual)
+		}
+	}
+}
+
+func TestParseVariable(t *testing.T) {
+	for _, v := range []Variable{VarCdA, VarCrr, VarMass, VarGrade, VarWind, VarRho, VarEfficiency} {
+		actual, err := ParseVariable(v.String())
+		if err != nil || actual != v {
+			t.Errorf("ParseVariable(%q): got: %d (%v), want: %d", v.String(), actual, err, v)
+		}
+	}
+	if _, err := ParseVariable("foo"); err == nil {
+		t.Errorf("ParseVariable(%q): expected error", "foo")
+	}
+}
@@ -0,0 +1,140 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// Axis is a dimension of a sweep over which an input is varied.
+type Axis struct {
+	// Input is the name of the input being varied: either the name of a
+	// Variable, 'p' for the power in watts or 't' for the time in seconds.
+	Input string
+	// Values are the values of the input.
+	Values []float64
+}
+
+// Output is the quantity calculated by a sweep.
+type Output int
+
+const (
+	// OutputSpeed is the average ground velocity in m/s at constant power.
+	OutputSpeed Output = iota
+	// OutputTime is the time in seconds at constant power.
+	OutputTime
+	// OutputPower is the constant net total power in watts required to ride
+	// the course in a given time.
+	OutputPower
+)
+
+// Cell is the result of evaluating a single combination of inputs of a sweep.
+type Cell struct {
+	// Inputs are the values of the input of each Axis.
+	Inputs []float64
+	// Value is the calculated Output, or NaN if there is no solution.
+	Value float64
+}
+
+// Sweep evaluates the Output out for Model m riding the course over the
+// Cartesian product of the values of each Axis, in parallel. The power p is
+// used for OutputSpeed and OutputTime and the time t for OutputPower unless they
+// are varied by an Axis. The cells are returned in row-major order, i.e. the
+// values of the last Axis vary the fastest.
+func (c Course) Sweep(m Model, p, t float64, out Output, axes ...Axis) ([]Cell, error) {
+	if len(c) == 0 {
+		return nil, fmt.Errorf("course has no segments")
+	}
+	if out < OutputSpeed || out > OutputPower {
+		return nil, fmt.Errorf("invalid output %d", out)
+	}
+
+	n := 1
+	vars := make([]Variable, len(axes))
+	seen := make(map[string]bool)
+	for i, a := range axes {
+		if seen[a.Input] {
+			return nil, fmt.Errorf("duplicate axis '%s'", a.Input)
+		}
+		seen[a.Input] = true
+		if len(a.Values) == 0 {
+			return nil, fmt.Errorf("axis '%s' has no values", a.Input)
+		}
+		n *= len(a.Values)
+
+		switch a.Input {
+		case "p":
+			if out == OutputPower {
+				return nil, fmt.Errorf("power can't be varied when calculating power")
+			}
+			vars[i] = -1
+		case "t":
+			if out != OutputPower {
+				return nil, fmt.Errorf("time can only be varied when calculating power")
+			}
+			vars[i] = -1
+		default:
+			v, err := ParseVariable(a.Input)
+			if err != nil {
+				return nil, err
+			}
+			vars[i] = v
+		}
+	}
+
+	cells := make([]Cell, n)
+	eval := func(k int) {
+		mk, ck, pk, tk := m, c, p, t
+		inputs := make([]float64, len(axes))
+		// decompose k into an index for each axis, the last varying fastest
+		for i, j := len(axes)-1, k; i >= 0; i-- {
+			x := axes[i].Values[j%len(axes[i].Values)]
+			j /= len(axes[i].Values)
+			inputs[i] = x
+			switch {
+			case axes[i].Input == "p":
+				pk = x
+			case axes[i].Input == "t":
+				tk = x
+			default:
+				mk, ck = vars[i].Set(mk, ck, x)
+			}
+		}
+
+		var r Result
+		var err error
+		if out == OutputPower {
+			r, err = ck.Power(mk, tk)
+		} else {
+			r, err = ck.Time(mk, pk)
+		}
+
+		value := math.NaN()
+		if err == nil {
+			switch out {
+			case OutputSpeed:
+				value = r.Speed()
+			case OutputTime:
+				value = r.Time
+			case OutputPower:
+				value = r.Power
+			}
+		}
+		cells[k] = Cell{Inputs: inputs, Value: value}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for k := w; k < n; k += workers {
+				eval(k)
+			}
+		}(w)
+	}
+	wg.Wait()
+	return cells, nil
+}
@@ -0,0 +1,72 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCourseSweep(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Course{{Length: 5000}}
+	ps, grs := []float64{200, 250, 300}, []float64{0, 0.05}
+
+	cells, err := c.Sweep(m, 0, 0, OutputSpeed, Axis{"p", ps}, Axis{"gr", grs})
+	if err != nil || len(cells) != len(ps)*len(grs) {
+		t.Fatalf("%v.Sweep(p, gr): got: %d cells (%v), want: %d", c, len(cells), err, len(ps)*len(grs))
+	}
+	for i, p := range ps {
+		for j, gr := range grs {
+			cell := cells[i*len(grs)+j]
+			mx, cx := VarGrade.Set(m, c, gr)
+			r, _ := cx.Time(mx, p)
+			if cell.Inputs[0] != p || cell.Inputs[1] != gr || !Eqf(cell.Value, r.Speed()) {
+				t.Errorf("%v.Sweep(p, gr)[%d]: got: %+v, want: {[%.3f %.3f] %.3f}",
+					c, i*len(grs)+j, cell, p, gr, r.Speed())
+			}
+		}
+	}
+
+	ts := []float64{600, 900}
+	cells, err = c.Sweep(m, 0, 0, OutputPower, Axis{"t", ts}, Axis{"mt", []float64{70}})
+	if err != nil || len(cells) != len(ts) {
+		t.Fatalf("%v.Sweep(t, mt): got: %d cells (%v), want: %d", c, len(cells), err, len(ts))
+	}
+	for i, tt := range ts {
+		mx, _ := VarMass.Set(m, c, 70)
+		r, _ := c.Power(mx, tt)
+		if !Eqf(cells[i].Value, r.Power) {
+			t.Errorf("%v.Sweep(t, mt)[%d]: got: %.3f, want: %.3f", c, i, cells[i].Value, r.Power)
+		}
+	}
+
+	cells, err = c.Sweep(m, 250, 0, OutputTime, Axis{"gr", []float64{0.1, -0.1}})
+	r, _ := Course{{Length: 5000, Grade: 0.1}}.Time(m, 250)
+	if err != nil || !Eqf(cells[0].Value, r.Time) || cells[1].Value >= cells[0].Value {
+		t.Errorf("%v.Sweep(gr): got: %v (%v), want: %.3f then faster downhill", c, cells, err, r.Time)
+	}
+	cells, err = c.Sweep(m, -100, 0, OutputTime, Axis{"gr", []float64{0}})
+	if err != nil || !math.IsNaN(cells[0].Value) {
+		t.Errorf("%v.Sweep(gr) @ -100 W: got: %v (%v), want: NaN", c, cells, err)
+	}
+}
+
+func TestCourseSweepInvalid(t *testing.T) {
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Course{{Length: 5000}}
+	tests := []struct {
+		out  Output
+		axes []Axis
+	}{
+		{OutputTime, []Axis{{"p", []float64{200}}, {"p", []float64{300}}}},
+		{OutputTime, []Axis{{"foo", []float64{200}}}},
+		{OutputTime, []Axis{{"cda", nil}}},
+		{OutputPower, []Axis{{"p", []float64{200}}}},
+		{OutputSpeed, []Axis{{"t", []float64{200}}}},
+		{Output(-1), nil},
+	}
+	for _, tt := range tests {
+		if _, err := c.Sweep(m, 200, 600, tt.out, tt.axes...); err == nil {
+			t.Errorf("%v.Sweep(%d, %v): expected error", c, tt.out, tt.axes)
+		}
+	}
+}
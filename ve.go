@@ -0,0 +1,219 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Estimate is an estimate of the aerodynamic drag and rolling resistance of a
+// rider derived from ride data.
+type Estimate struct {
+	// CdA is the estimated coefficient of drag area in squared metres.
+	CdA float64
+	// Crr is the estimated coefficient of rolling resistance.
+	Crr float64
+	// RMSE is the root mean squared error of the fit, in metres for virtual
+	// elevation and in watts for regression.
+	RMSE float64
+}
+
+// ve holds the terms of the virtual elevation profile which allow it to be
+// evaluated for any CdA and Crr: the change in elevation over each interval is
+// base - cda*at - crr*rr.
+type ve struct {
+	h0           float64
+	base, at, rr []float64
+}
+
+// VirtualElevation calculates the virtual elevation profile in metres of the
+// Records rs using Chung's method: the power required to overcome gravity over
+// each interval is whatever is left over after subtracting the power required
+// by Pat, Prr, Pwb and Pke (calculated using the CdA and Crr of the Model m)
+// from the power produced. The profile starts at the altitude of the first
+// record (or 0 if not recorded).
+//
+// If the Model's Environment does not specify an air density (Rho is 0), the air
+// density is calculated for each record using Record.Rho. The heading used to
+// calculate the air velocity is derived from the position of the records.
+func (m Model) VirtualElevation(rs []Record) ([]float64, error) {
+	v, err := m.ve(rs)
+	if err != nil {
+		return nil, err
+	}
+	return v.profile(m.Rider.CdA, m.Bike.Crr), nil
+}
+
+// FitVirtualElevation estimates the CdA and Crr of the Model m which minimize
+// the difference between the virtual elevation profile of the Records rs and
+// their recorded altitude.
+func (m Model) FitVirtualElevation(rs []Record) (Estimate, error) {
+	v, err := m.ve(rs)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	// VE_k = h0 + sum(base) - cda*sum(at) - crr*sum(rr), fit against altitude
+	var x [][]float64
+	var y []float64
+	sb, sa, sr := 0.0, 0.0, 0.0
+	for k, r := range rs {
+		if k > 0 {
+			sb, sa, sr = sb+v.base[k-1], sa+v.at[k-1], sr+v.rr[k-1]
+		}
+		if math.IsNaN(r.Altitude) {
+			continue
+		}
+		x = append(x, []float64{1, -sa, -sr})
+		y = append(y, r.Altitude-sb)
+	}
+	if len(y) < 3 {
+		return Estimate{}, fmt.Errorf("at least 3 records with altitude are required but only %d were provided", len(y))
+	}
+
+	beta, _, err := lstsq(x, y)
+	if err != nil {
+		return Estimate{}, err
+	}
+	v.h0 = beta[0]
+	return Estimate{CdA: beta[1], Crr: beta[2], RMSE: v.rmse(rs, beta[1], beta[2])}, nil
+}
+
+// FitVirtualElevationLaps estimates the CdA and Crr of the Model m from the
+// Records rs which are made up of laps of a loop, such that each lap must
+// start and finish at the same elevation. The laps are delimited by the
+// indices of the records in laps, e.g. {0, 120, 240} describes two laps. If
+// there is only a single lap the Crr of the Model's Bike is assumed and only
+// the CdA is estimated. The RMSE is the error in elevation gained per lap.
+func (m Model) FitVirtualElevationLaps(rs []Record, laps []int) (Estimate, error) {
+	if len(laps) < 2 {
+		return Estimate{}, fmt.Errorf("at least one lap is required")
+	}
+	for i, l := range laps {
+		if l < 0 || l >= len(rs) || (i > 0 && l <= laps[i-1]) {
+			return Estimate{}, fmt.Errorf("invalid lap index %d", l)
+		}
+	}
+
+	v, err := m.ve(rs)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	// the virtual elevation gained over each lap must be 0
+	var x [][]float64
+	var y []float64
+	for i := 1; i < len(laps); i++ {
+		b, a, r := 0.0, 0.0, 0.0
+		for k := laps[i-1]; k < laps[i]; k++ {
+			b, a, r = b+v.base[k], a+v.at[k], r+v.rr[k]
+		}
+		x = append(x, []float64{a, r})
+		y = append(y, b)
+	}
+
+	var est Estimate
+	if len(y) == 1 {
+		if x[0][0] == 0 {
+			return Estimate{}, ErrNoSolution
+		}
+		est = Estimate{CdA: (y[0] - m.Bike.Crr*x[0][1]) / x[0][0], Crr: m.Bike.Crr}
+	} else {
+		beta, _, err := lstsq(x, y)
+		if err != nil {
+			return Estimate{}, err
+		}
+		est = Estimate{CdA: beta[0], Crr: beta[1]}
+	}
+
+	sse := 0.0
+	for i := range y {
+		e := y[i] - est.CdA*x[i][0] - est.Crr*x[i][1]
+		sse += e * e
+	}
+	est.RMSE = math.Sqrt(sse / float64(len(y)))
+	return est, nil
+}
+
+// ve calculates the terms of the virtual elevation profile of rs.
+func (m Model) ve(rs []Record) (*ve, error) {
+	if len(rs) < 2 {
+		return nil, fmt.Errorf("at least 2 records are required but only %d were provided", len(rs))
+	}
+
+	mt, g, b := m.Mass(), m.Environment.G, m.Bike
+	v := &ve{
+		base: make([]float64, len(rs)-1),
+		at:   make([]float64, len(rs)-1),
+		rr:   make([]float64, len(rs)-1),
+	}
+	if !math.IsNaN(rs[0].Altitude) {
+		v.h0 = rs[0].Altitude
+	}
+
+	heading := 0.0
+	for i := 1; i < len(rs); i++ {
+		prev, cur := rs[i-1], rs[i]
+		dt := cur.Time.Sub(prev.Time).Seconds()
+		if dt <= 0 {
+			continue
+		}
+
+		vgi, vgf := prev.Speed, cur.Speed
+		if math.IsNaN(vgi) || math.IsNaN(vgf) {
+			if math.IsNaN(prev.Distance) || math.IsNaN(cur.Distance) {
+				return nil, fmt.Errorf("record %d is missing speed and distance", i)
+			}
+			vgi = (cur.Distance - prev.Distance) / dt
+			vgf = vgi
+		}
+		vg := (vgi + vgf) / 2
+
+		p := cur.Power
+		if math.IsNaN(p) {
+			p = 0
+		}
+		rho := m.Environment.Rho
+		if rho == 0 {
+			rho = cur.Rho()
+		}
+		from, to := Waypoint{Lat: prev.Lat, Lon: prev.Lon}, Waypoint{Lat: cur.Lat, Lon: cur.Lon}
+		if !math.IsNaN(from.Lat+from.Lon+to.Lat+to.Lon) && from != to {
+			heading = Bearing(from, to)
+		}
+		va := m.Va(vg, Conditions{Heading: heading})
+
+		// the power available to overcome gravity is converted into elevation
+		// gained using Ppe = vg * mt * g * sin(atan(gr)) ~ mt * g * dh/dt
+		f := dt / (mt * g)
+		ke := m.pke(vgi, vgf, 0, dt) * b.DrivetrainEfficiency
+		v.base[i-1] = (p*b.DrivetrainEfficiency - Pat(rho, 0, b.Fw, va, vg) - Pwb(vg) - ke) * f
+		v.at[i-1] = Pat(rho, 1, 0, va, vg) * f
+		v.rr[i-1] = Prr(vg, 0, 1, mt, g) * f
+	}
+
+	return v, nil
+}
+
+// profile returns the virtual elevation profile given cda and crr.
+func (v *ve) profile(cda, crr float64) []float64 {
+	h := make([]float64, len(v.base)+1)
+	h[0] = v.h0
+	for i := range v.base {
+		h[i+1] = h[i] + v.base[i] - cda*v.at[i] - crr*v.rr[i]
+	}
+	return h
+}
+
+// rmse returns the root mean squared error between the virtual elevation
+// profile given cda and crr and the recorded altitude of rs.
+func (v *ve) rmse(rs []Record, cda, crr float64) float64 {
+	sse, n := 0.0, 0
+	for i, h := range v.profile(cda, crr) {
+		if math.IsNaN(rs[i].Altitude) {
+			continue
+		}
+		sse += math.Pow(h-rs[i].Altitude, 2)
+		n++
+	}
+	return math.Sqrt(sse / float64(n))
+}
@@ -0,0 +1,88 @@
+package calc
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// testRide simulates riding laps of a rolling loop with the power varying both
+// within and between laps, returning the records sampled every second and the
+// indices of the start of each lap.
+func testRide(m Model, laps int) ([]Record, []int) {
+	const loop = 2000.0
+	grade := func(d float64) float64 {
+		return 0.04 * math.Sin(2*math.Pi*d/loop)
+	}
+	points, _ := m.Simulate(Simulation{
+		Power: func(t, d, vg float64) float64 {
+			return 150 + 100*math.Floor(d/loop) + 50*math.Sin(2*math.Pi*t/150)
+		},
+		Conditions: func(d float64) Conditions { return Conditions{Grade: grade(d)} },
+		Step:       1,
+		Integrator: RK4,
+		Speed:      8,
+		Distance:   loop * float64(laps),
+	})
+
+	start := time.Date(2018, time.June, 1, 8, 0, 0, 0, time.UTC)
+	nan := math.NaN()
+	var rs []Record
+	var idx []int
+	h, d := 0.0, 0.0
+	for _, p := range points {
+		// integrate the altitude along the road
+		for ; d < p.Distance; d += 0.1 {
+			h += 0.1 * math.Sin(math.Atan(grade(d)))
+		}
+		if p.Distance >= loop*float64(len(idx)) {
+			idx = append(idx, len(rs))
+		}
+		rs = append(rs, Record{
+			Time:     start.Add(time.Duration(p.Time * float64(time.Second))),
+			Power:    p.Power,
+			Speed:    p.Speed,
+			Distance: p.Distance,
+			Altitude: h,
+			Lat:      nan, Lon: nan, Temperature: nan, Cadence: nan, HeartRate: nan,
+		})
+	}
+	return rs, idx
+}
+
+func TestVirtualElevation(t *testing.T) {
+	m := testModel(67, 8, 0.28, 0.005, Rho0, 0, 0)
+	rs, _ := testRide(m, 1)
+	ve, err := m.VirtualElevation(rs)
+	if err != nil {
+		t.Fatalf("VirtualElevation: got: %v", err)
+	}
+	for i := range rs {
+		if math.Abs(ve[i]-rs[i].Altitude) > 0.5 {
+			t.Errorf("VirtualElevation: record %d got: %.3f m, want: %.3f m", i, ve[i], rs[i].Altitude)
+			break
+		}
+	}
+}
+
+func TestFitVirtualElevation(t *testing.T) {
+	actual := testModel(67, 8, 0.28, 0.005, Rho0, 0, 0)
+	rs, laps := testRide(actual, 3)
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+
+	est, err := m.FitVirtualElevation(rs)
+	if err != nil || !Eqf(est.CdA, 0.28, 1e-2) || !Eqf(est.Crr, 0.005, 2e-2) {
+		t.Errorf("FitVirtualElevation: got: %+v (%v), want: {CdA:0.28 Crr:0.005}", est, err)
+	}
+
+	est, err = m.FitVirtualElevationLaps(rs, laps)
+	if err != nil || !Eqf(est.CdA, 0.28, 1e-2) || !Eqf(est.Crr, 0.005, 2e-2) {
+		t.Errorf("FitVirtualElevationLaps(%v): got: %+v (%v), want: {CdA:0.28 Crr:0.005}", laps, est, err)
+	}
+
+	m.Bike.Crr = 0.005
+	est, err = m.FitVirtualElevationLaps(rs, laps[:2])
+	if err != nil || !Eqf(est.CdA, 0.28, 1e-2) || est.Crr != 0.005 {
+		t.Errorf("FitVirtualElevationLaps(%v): got: %+v (%v), want: {CdA:0.28 Crr:0.005}", laps[:2], est, err)
+	}
+}
@@ -0,0 +1,136 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrExhausted is returned when a performance would require more work above
+// critical power than the rider's W′.
+var ErrExhausted = errors.New("W′ exhausted")
+
+// CriticalPower describes the power-duration relationship of a rider using the
+// critical power model: a rider can produce CP watts for a long time, and any
+// power above CP depletes a finite work capacity W′ which is reconstituted while
+// riding below CP.
+type CriticalPower struct {
+	// CP is the critical power in watts.
+	CP float64
+	// WPrime is the work capacity above CP in joules.
+	WPrime float64
+}
+
+// Recovery is the model used to calculate the reconstitution of W′.
+type Recovery int
+
+const (
+	// SkibaDifferential is the differential model of Skiba et al. (2015), in
+	// which the rate of reconstitution is proportional to both the amount of W′
+	// expended and how far below CP the rider is.
+	SkibaDifferential Recovery = iota
+	// SkibaIntegral is the integral model of Skiba et al. (2012), in which each
+	// expenditure of W′ recovers exponentially with a time constant depending
+	// on the average power below CP.
+	SkibaIntegral
+	// Bartram is the integral model with the time constant refit by Bartram et
+	// al. (2018) for elite cyclists.
+	Bartram
+)
+
+// WBal calculates the balance of W′ remaining in joules after each sample of
+// the power series which has been recorded every dt seconds.
+func (cp CriticalPower) WBal(power []float64, dt float64, r Recovery) ([]float64, error) {
+	if dt <= 0 {
+		return nil, fmt.Errorf("dt must be positive but was %f", dt)
+	}
+	dts := make([]float64, len(power))
+	for i := range dts {
+		dts[i] = dt
+	}
+	return cp.wbal(power, dts, r)
+}
+
+// Check verifies that the performance r (e.g. as predicted by Course.Time)
+// never drains the W′ of the rider below zero, returning the minimum W′bal in
+// joules reached over the course of the performance. ErrExhausted is returned
+// along with the minimum if the W′ is exhausted.
+func (cp CriticalPower) Check(r Result, rec Recovery) (float64, error) {
+	// step is the maximum duration in seconds of each sample
+	const step = 1.0
+
+	var power, dts []float64
+	for _, s := range r.Splits {
+		n := math.Ceil(s.Time / step)
+		for i := 0.0; i < n; i++ {
+			power = append(power, s.Power)
+			dts = append(dts, s.Time/n)
+		}
+	}
+
+	bal, err := cp.wbal(power, dts, rec)
+	if err != nil {
+		return 0, err
+	}
+	min := cp.WPrime
+	for _, b := range bal {
+		min = math.Min(min, b)
+	}
+	if min < 0 {
+		return min, ErrExhausted
+	}
+	return min, nil
+}
+
+// wbal calculates W′bal after each sample of power, where sample i lasts for
+// dts[i] seconds.
+func (cp CriticalPower) wbal(power, dts []float64, r Recovery) ([]float64, error) {
+	if cp.CP <= 0 || cp.WPrime <= 0 {
+		return nil, fmt.Errorf("invalid critical power %+v", cp)
+	}
+
+	bal := make([]float64, len(power))
+	switch r {
+	case SkibaDifferential:
+		b := cp.WPrime
+		for i, p := range power {
+			if p > cp.CP {
+				b -= (p - cp.CP) * dts[i]
+			} else {
+				b = cp.WPrime - (cp.WPrime-b)*math.Exp(-(cp.CP-p)*dts[i]/cp.WPrime)
+			}
+			bal[i] = b
+		}
+	case SkibaIntegral, Bartram:
+		// dcp is the average difference between CP and the power below CP
+		dcp, t := 0.0, 0.0
+		for i, p := range power {
+			if p < cp.CP {
+				dcp += (cp.CP - p) * dts[i]
+				t += dts[i]
+			}
+		}
+		if t > 0 {
+			dcp /= t
+		}
+
+		tau := 546*math.Exp(-0.01*dcp) + 316
+		if r == Bartram {
+			tau = 2287.2 * math.Pow(dcp, -0.688)
+		}
+
+		// the sum of each expenditure decayed since it occurred can be
+		// calculated incrementally as the time constant is fixed
+		expended := 0.0
+		for i, p := range power {
+			expended *= math.Exp(-dts[i] / tau)
+			if p > cp.CP {
+				expended += (p - cp.CP) * dts[i]
+			}
+			bal[i] = cp.WPrime - expended
+		}
+	default:
+		return nil, fmt.Errorf("invalid recovery model %d", r)
+	}
+	return bal, nil
+}
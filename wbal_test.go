@@ -0,0 +1,84 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestWBal(t *testing.T) {
+	cp := CriticalPower{CP: 250, WPrime: 20000}
+	var power []float64
+	for i := 0; i < 160; i++ {
+		if i < 60 {
+			power = append(power, 350)
+		} else {
+			power = append(power, 150)
+		}
+	}
+
+	tests := []struct {
+		r               Recovery
+		after, expected float64
+	}{
+		{SkibaDifferential, 14000, 16360.816},
+		{SkibaIntegral, 14329.680, 15327.144},
+		{Bartram, 15512.302, 18412.520},
+	}
+	for _, tt := range tests {
+		bal, err := cp.WBal(power, 1, tt.r)
+		if err != nil {
+			t.Errorf("%+v.WBal(power, 1, %d): got: %v", cp, tt.r, err)
+			continue
+		}
+		if !Eqf(bal[59], tt.after) || !Eqf(bal[159], tt.expected) {
+			t.Errorf("%+v.WBal(power, 1, %d): got: %.3f %.3f, want: %.3f %.3f",
+				cp, tt.r, bal[59], bal[159], tt.after, tt.expected)
+		}
+	}
+}
+
+func TestWBalInvalid(t *testing.T) {
+	tests := []struct {
+		cp CriticalPower
+		dt float64
+		r  Recovery
+	}{
+		{CriticalPower{CP: 250, WPrime: 20000}, 0, SkibaDifferential},
+		{CriticalPower{CP: 0, WPrime: 20000}, 1, SkibaDifferential},
+		{CriticalPower{CP: 250, WPrime: 20000}, 1, Recovery(-1)},
+	}
+	for _, tt := range tests {
+		if _, err := tt.cp.WBal([]float64{300}, tt.dt, tt.r); err == nil {
+			t.Errorf("%+v.WBal({300}, %.3f, %d): expected error", tt.cp, tt.dt, tt.r)
+		}
+	}
+}
+
+func TestCriticalPowerCheck(t *testing.T) {
+	r := Result{Splits: []Split{{Time: 60, Power: 350}, {Time: 100.5, Power: 150}}}
+	tests := []struct {
+		cp       CriticalPower
+		expected float64
+		err      error
+	}{
+		{CriticalPower{CP: 250, WPrime: 20000}, 14000, nil},
+		{CriticalPower{CP: 250, WPrime: 5000}, -1000, ErrExhausted},
+		{CriticalPower{CP: 400, WPrime: 5000}, 5000, nil},
+	}
+	for _, tt := range tests {
+		actual, err := tt.cp.Check(r, SkibaDifferential)
+		if err != tt.err || !Eqf(actual, tt.expected) {
+			t.Errorf("%+v.Check(%+v): got: %.3f (%v), want: %.3f (%v)",
+				tt.cp, r, actual, err, tt.expected, tt.err)
+		}
+	}
+
+	m := testModel(67, 8, DropsCdA, Crr, Rho0, 0, 0)
+	c := Course{{Length: 1000, Grade: 0.08}, {Length: 5000, Grade: -0.02}}
+	res, err := c.Time(m, 300)
+	if err != nil {
+		t.Fatalf("%+v.Time(300): got: %v", c, err)
+	}
+	if _, err := (CriticalPower{CP: 280, WPrime: 1000}).Check(res, SkibaIntegral); err != ErrExhausted {
+		t.Errorf("Check(%+v): got: %v, want: %v", res, err, ErrExhausted)
+	}
+}
@@ -0,0 +1,114 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CdACurve describes how the CdA of a rider varies with the yaw angle.
+type CdACurve interface {
+	// CdA returns the CdA in squared metres at a yaw angle of yaw degrees.
+	CdA(yaw float64) float64
+}
+
+// YawPoint is a measurement of the CdA at a particular yaw angle.
+type YawPoint struct {
+	// Yaw is the yaw angle in degrees.
+	Yaw float64
+	// CdA is the CdA in squared metres.
+	CdA float64
+}
+
+// TabulatedCdA is a CdACurve which linearly interpolates between measurements
+// sorted by yaw angle. The CdA is assumed to be symmetric about 0 yaw and is
+// held constant beyond the first and last measurements.
+type TabulatedCdA []YawPoint
+
+// CdA returns the CdA at a yaw angle of yaw degrees.
+func (t TabulatedCdA) CdA(yaw float64) float64 {
+	if len(t) == 0 {
+		return 0
+	}
+
+	yaw = math.Abs(yaw)
+	i := sort.Search(len(t), func(i int) bool { return t[i].Yaw >= yaw })
+	if i == 0 {
+		return t[0].CdA
+	}
+	if i == len(t) {
+		return t[len(t)-1].CdA
+	}
+
+	lo, hi := t[i-1], t[i]
+	return lo.CdA + (hi.CdA-lo.CdA)*(yaw-lo.Yaw)/(hi.Yaw-lo.Yaw)
+}
+
+// QuadraticCdA is a parametric CdACurve where the CdA at yaw angle y (in
+// degrees) is C0 + C1*|y| + C2*y^2.
+type QuadraticCdA struct {
+	C0, C1, C2 float64
+}
+
+// CdA returns the CdA at a yaw angle of yaw degrees.
+func (q QuadraticCdA) CdA(yaw float64) float64 {
+	yaw = math.Abs(yaw)
+	return q.C0 + q.C1*yaw + q.C2*yaw*yaw
+}
+
+// FitCdACurve fits a QuadraticCdA to the measurements pts using least squares.
+func FitCdACurve(pts []YawPoint) (QuadraticCdA, error) {
+	if len(pts) < 3 {
+		return QuadraticCdA{}, fmt.Errorf("at least 3 points are required but only %d were provided", len(pts))
+	}
+
+	x := make([][]float64, len(pts))
+	y := make([]float64, len(pts))
+	for i, p := range pts {
+		yaw := math.Abs(p.Yaw)
+		x[i] = []float64{1, yaw, yaw * yaw}
+		y[i] = p.CdA
+	}
+
+	beta, _, err := lstsq(x, y)
+	if err != nil {
+		return QuadraticCdA{}, err
+	}
+	return QuadraticCdA{C0: beta[0], C1: beta[1], C2: beta[2]}, nil
+}
+
+type scaledCdA struct {
+	curve CdACurve
+	f     float64
+}
+
+func (s scaledCdA) CdA(yaw float64) float64 {
+	return s.f * s.curve.CdA(yaw)
+}
+
+// ScaleCdA returns the CdACurve with the same shape as c but scaled such that
+// the CdA at 0 yaw is cda, e.g. to apply one of the typical curves below to a
+// rider with a known CdA.
+func ScaleCdA(c CdACurve, cda float64) CdACurve {
+	return scaledCdA{curve: c, f: cda / c.CdA(0)}
+}
+
+// Typical CdA curves for various cycling positions. Riders sitting up are
+// mostly affected by the increasing frontal area presented at higher yaw
+// angles, whereas in the aerobars with deep section wheels the sail effect
+// reduces the drag at moderate yaw angles. These are approximations of the
+// general shape of published wind tunnel data rather than measurements.
+var (
+	TopsCdACurve = TabulatedCdA{
+		{0, TopsCdA}, {5, 0.402}, {10, 0.408}, {15, 0.416}, {20, 0.426}, {30, 0.450},
+	}
+	HoodsCdACurve = TabulatedCdA{
+		{0, HoodsCdA}, {5, 0.352}, {10, 0.357}, {15, 0.364}, {20, 0.373}, {30, 0.394},
+	}
+	DropsCdACurve = TabulatedCdA{
+		{0, DropsCdA}, {5, 0.311}, {10, 0.315}, {15, 0.321}, {20, 0.329}, {30, 0.348},
+	}
+	TTAeroCdACurve = TabulatedCdA{
+		{0, TTAeroCdA}, {5, 0.246}, {10, 0.241}, {15, 0.240}, {20, 0.244}, {30, 0.262},
+	}
+)
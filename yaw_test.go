@@ -0,0 +1,101 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestTabulatedCdA(t *testing.T) {
+	tests := []struct {
+		yaw, expected float64
+	}{
+		{0, 0.250},
+		{5, 0.246},
+		{-5, 0.246},
+		{7.5, 0.2435},
+		{25, 0.253},
+		{45, 0.262},
+	}
+	for _, tt := range tests {
+		actual := TTAeroCdACurve.CdA(tt.yaw)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("TTAeroCdACurve.CdA(%.3f): got: %.3f, want: %.3f", tt.yaw, actual, tt.expected)
+		}
+	}
+}
+
+func TestQuadraticCdA(t *testing.T) {
+	q := QuadraticCdA{C0: 0.3, C1: 0.001, C2: 0.0001}
+	tests := []struct {
+		yaw, expected float64
+	}{
+		{0, 0.3},
+		{10, 0.32},
+		{-10, 0.32},
+	}
+	for _, tt := range tests {
+		actual := q.CdA(tt.yaw)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("%+v.CdA(%.3f): got: %.3f, want: %.3f", q, tt.yaw, actual, tt.expected)
+		}
+	}
+}
+
+func TestFitCdACurve(t *testing.T) {
+	expected := QuadraticCdA{C0: 0.3, C1: 0.001, C2: 0.0001}
+	var pts []YawPoint
+	for _, yaw := range []float64{-20, -10, 0, 5, 15, 30} {
+		pts = append(pts, YawPoint{Yaw: yaw, CdA: expected.CdA(yaw)})
+	}
+	actual, err := FitCdACurve(pts)
+	if err != nil || !Eqf(actual.C0, expected.C0) || !Eqf(actual.C1, expected.C1) || !Eqf(actual.C2, expected.C2) {
+		t.Errorf("FitCdACurve(%+v): got: %+v (%v), want: %+v", pts, actual, err, expected)
+	}
+
+	if _, err := FitCdACurve(pts[:2]); err == nil {
+		t.Errorf("FitCdACurve(%+v): expected error", pts[:2])
+	}
+}
+
+func TestScaleCdA(t *testing.T) {
+	c := ScaleCdA(DropsCdACurve, 0.279)
+	tests := []struct {
+		yaw, expected float64
+	}{
+		{0, 0.279},
+		{30, 0.348 * 0.9},
+	}
+	for _, tt := range tests {
+		actual := c.CdA(tt.yaw)
+		if !Eqf(actual, tt.expected) {
+			t.Errorf("ScaleCdA(DropsCdACurve, 0.279).CdA(%.3f): got: %.3f, want: %.3f", tt.yaw, actual, tt.expected)
+		}
+	}
+}
+
+func TestModelYaw(t *testing.T) {
+	m := testModel(67, 8, TTAeroCdA, Crr, Rho0, 4, 90)
+	m.Rider.Curve = TTAeroCdACurve
+	c := Conditions{Heading: 0}
+
+	tests := []struct {
+		m        Model
+		p        float64
+		expected float64
+	}{
+		{m, 250, 11.122},
+		{testModel(67, 8, TTAeroCdA, Crr, Rho0, 4, 90), 250, 11.036},
+	}
+	for _, tt := range tests {
+		vg, err := tt.m.Speed(tt.p, c)
+		if err != nil || !Eqf(vg, tt.expected) {
+			t.Errorf("%+v.Speed(%.3f, %+v): got: %.3f, want: %.3f", tt.m, tt.p, c, vg, tt.expected)
+		}
+		if p := tt.m.Power(vg, c); !Eqf(p, tt.p) {
+			t.Errorf("%+v.Power(%.3f, %+v): got: %.3f, want: %.3f", tt.m, vg, c, p, tt.p)
+		}
+	}
+
+	if cda := m.CdA(0, Conditions{Heading: 90}); !Eqf(cda, TTAeroCdA) {
+		t.Errorf("%+v.CdA(0, {0, 90}): got: %.3f, want: %.3f", m, cda, TTAeroCdA)
+	}
+}